@@ -0,0 +1,152 @@
+package cloudglog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// adminState is the JSON document served by the admin handler
+type adminState struct {
+	Level   int    `json:"level"`
+	VModule string `json:"vmodule"`
+	Trace   bool   `json:"trace"`
+	Format  string `json:"format"`
+	Color   string `json:"color"`
+}
+
+// adminHandler shows and changes the settings of a Logger, see Logger.Handler
+type adminHandler struct {
+	l *Logger
+}
+
+// Handler returns an http.Handler that shows and changes the V() level,
+// vmodule, TRACE log, format and color style of l at runtime.
+//
+// GET returns the settings as JSON, PUT and POST change the settings
+// given as query or form values and return the new settings:
+//
+//	curl -X PUT 'localhost:8080/debug/loglevel?level=2&vmodule=server=3&trace=true'
+//	curl -X PUT 'localhost:8080/debug/loglevel?format=json&color=NoColor'
+//
+// All values are checked before any is applied, a bad value changes
+// nothing and is answered with 400 Bad Request. An empty vmodule
+// removes all vmodule entries.
+func (l *Logger) Handler() http.Handler {
+	return adminHandler{l}
+}
+
+// Handler returns an http.Handler for the settings of the default Logger,
+// see Logger.Handler.
+//
+// Example:
+//
+//	http.Handle("/debug/loglevel", cloudglog.Handler())
+func Handler() http.Handler {
+	return std.Handler()
+}
+
+func (h adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut, http.MethodPost:
+		if err := h.update(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(h.state())
+}
+
+// state returns the current settings of the Logger
+func (h adminHandler) state() adminState {
+
+	h.l.mu.Lock()
+	format, color := h.l.format, h.l.color
+	h.l.mu.Unlock()
+
+	return adminState{
+		Level:   h.l.GetLogLevel(),
+		VModule: h.l.VModule(),
+		Trace:   h.l.TraceEnabled(),
+		Format:  format.String(),
+		Color:   color.String(),
+	}
+}
+
+// update parses the values of r and applies them if all are valid
+func (h adminHandler) update(r *http.Request) error {
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	var apply []func()
+
+	if v, ok := formValue(r, "level"); ok {
+		level, err := strconv.Atoi(v)
+		if err != nil || level < 0 {
+			return fmt.Errorf("invalid level %q", v)
+		}
+		apply = append(apply, func() { h.l.SetLogLevel(level) })
+	}
+
+	if v, ok := formValue(r, "vmodule"); ok {
+		// parse now, SetVModule cannot fail afterwards
+		if strings.TrimSpace(v) != "" {
+			if _, err := parseVModule(v); err != nil {
+				return err
+			}
+		}
+		apply = append(apply, func() { h.l.SetVModule(v) })
+	}
+
+	if v, ok := formValue(r, "trace"); ok {
+		trace, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid trace %q", v)
+		}
+		apply = append(apply, func() { h.l.EnableTrace(trace) })
+	}
+
+	if v, ok := formValue(r, "format"); ok {
+		format, ok := formatByName(v)
+		if !ok {
+			return fmt.Errorf("unknown format %q", v)
+		}
+		apply = append(apply, func() { h.l.FormatStyle(format) })
+	}
+
+	if v, ok := formValue(r, "color"); ok {
+		color, ok := colorStyleByName(v)
+		if !ok {
+			return fmt.Errorf("unknown color style %q", v)
+		}
+		apply = append(apply, func() { h.l.ColorsStyle(color) })
+	}
+
+	for _, f := range apply {
+		f()
+	}
+
+	return nil
+}
+
+// formValue returns the value of key in r and whether it was given
+func formValue(r *http.Request, key string) (string, bool) {
+	v, ok := r.Form[key]
+	if !ok || len(v) == 0 {
+		return "", false
+	}
+	return v[0], true
+}
@@ -0,0 +1,71 @@
+package cloudglog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveAdmin(l *Logger, method, target string) (*httptest.ResponseRecorder, adminState) {
+
+	w := httptest.NewRecorder()
+	l.Handler().ServeHTTP(w, httptest.NewRequest(method, target, nil))
+
+	var state adminState
+	json.Unmarshal(w.Body.Bytes(), &state)
+
+	return w, state
+}
+
+func Test_Handler(t *testing.T) {
+
+	l := New(nil, DefaultFormat, NoColor, 1)
+
+	w, state := serveAdmin(l, http.MethodGet, "/debug/loglevel")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, adminState{Level: 1, Format: "default", Color: "NoColor"}, state)
+
+	w, state = serveAdmin(l, http.MethodPut, "/debug/loglevel?level=3&vmodule=server%3D4&trace=true&format=json&color=fullcolor")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, adminState{Level: 3, VModule: "server=4", Trace: true, Format: "json", Color: "FullColor"}, state)
+	assert.Equal(t, 3, l.GetLogLevel())
+	assert.True(t, l.TraceEnabled())
+	assert.Equal(t, JSONFormat, l.format)
+	assert.Equal(t, FullColor, l.color)
+
+	// an empty vmodule removes it
+	_, state = serveAdmin(l, http.MethodPost, "/debug/loglevel?vmodule=")
+	assert.Equal(t, "", state.VModule)
+}
+
+func Test_HandlerErrors(t *testing.T) {
+
+	l := New(nil, DefaultFormat, NoColor, 1)
+
+	for _, query := range []string{"level=x", "level=-1", "vmodule=bad", "trace=maybe", "format=xml", "color=pink"} {
+		w, _ := serveAdmin(l, http.MethodPut, "/debug/loglevel?"+query+"&trace=true")
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	// nothing was applied
+	assert.False(t, l.TraceEnabled())
+
+	w := httptest.NewRecorder()
+	l.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/debug/loglevel", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.True(t, strings.Contains(w.Header().Get("Allow"), "PUT"))
+}
+
+func Test_PackageHandler(t *testing.T) {
+
+	defer SetLogLevel(GetLogLevel())
+
+	serveAdmin(std, http.MethodPut, "/?level=7")
+	assert.Equal(t, 7, GetLogLevel())
+	assert.True(t, bool(V(7)))
+}
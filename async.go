@@ -0,0 +1,211 @@
+package cloudglog
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type asyncPolicy int
+
+const (
+	// asyncPolicy decides what an AsyncWriter does with a record when its queue is full
+	AsyncBlock      asyncPolicy = iota // wait until there is room
+	AsyncDropNewest                    // drop the record
+	AsyncDropOldest                    // drop the oldest queued record to make room
+)
+
+// errAsyncClosed is returned by Write after Close
+var errAsyncClosed = errors.New("cloudglog: write to closed AsyncWriter")
+
+// AsyncWriter is an io.WriteCloser that queues the records written to it
+// and writes them to its underlying writer on a background goroutine, so
+// a slow output does not hold up the goroutines that log. The queue holds
+// a bounded number of records, the policy decides what happens when it is
+// full.
+//
+// Flush waits until the queue is written, Fatal and Exit call it through
+// Logger.Flush before the process exits. Reopen reopens the underlying
+// writer for Logger.Reopen. Close flushes and stops the goroutine, it does
+// not close the underlying writer.
+//
+// Example:
+//
+//	a := cloudglog.NewAsyncWriter(os.Stdout, 4096, cloudglog.AsyncDropOldest)
+//	defer a.Close()
+//	cloudglog.LogFile(a)
+type AsyncWriter struct {
+	w       io.Writer
+	policy  asyncPolicy
+	dropped uint64 // accessed atomically
+
+	mu      sync.Mutex
+	cond    sync.Cond // signaled on every change of the queue
+	queue   [][]byte  // ring of queued records
+	head    int       // index of the oldest record
+	n       int       // number of queued records
+	free    [][]byte  // written records for reuse
+	writing bool      // a record is being written to w
+	closed  bool
+	err     error // first error of w since the last Flush
+	done    chan struct{}
+}
+
+// NewAsyncWriter returns an AsyncWriter that writes to w and queues up to
+// size records, a size below 1 is taken as 1.
+func NewAsyncWriter(w io.Writer, size int, policy asyncPolicy) *AsyncWriter {
+
+	if size < 1 {
+		size = 1
+	}
+
+	a := &AsyncWriter{
+		w:      w,
+		policy: policy,
+		queue:  make([][]byte, size),
+		done:   make(chan struct{}),
+	}
+	a.cond.L = &a.mu
+
+	go a.run()
+
+	return a
+}
+
+// Write queues a copy of p, it only blocks if the queue is full and the
+// policy is AsyncBlock. Dropped records are not an error.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for a.n == len(a.queue) && !a.closed {
+		switch a.policy {
+		case AsyncDropNewest:
+			atomic.AddUint64(&a.dropped, 1)
+			return len(p), nil
+		case AsyncDropOldest:
+			a.free = append(a.free, a.pop())
+			atomic.AddUint64(&a.dropped, 1)
+		default:
+			a.cond.Wait()
+		}
+	}
+
+	if a.closed {
+		return 0, errAsyncClosed
+	}
+
+	var b []byte
+	if k := len(a.free); k > 0 {
+		b, a.free = a.free[k-1], a.free[:k-1]
+	}
+	a.queue[(a.head+a.n)%len(a.queue)] = append(b[:0], p...)
+	a.n++
+	a.cond.Broadcast()
+
+	return len(p), nil
+}
+
+// pop removes the oldest record from the queue, a.mu must be held.
+func (a *AsyncWriter) pop() []byte {
+	b := a.queue[a.head]
+	a.queue[a.head] = nil
+	a.head = (a.head + 1) % len(a.queue)
+	a.n--
+	return b
+}
+
+// run writes the queued records to w until the AsyncWriter is closed
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for {
+		for a.n == 0 && !a.closed {
+			a.cond.Wait()
+		}
+		if a.n == 0 {
+			return
+		}
+
+		b := a.pop()
+		w := a.w // replaced by Reopen while nothing is written
+		a.writing = true
+		a.cond.Broadcast()
+		a.mu.Unlock()
+
+		_, err := w.Write(b)
+
+		a.mu.Lock()
+		a.writing = false
+		if err != nil && a.err == nil {
+			a.err = err
+		}
+		if len(a.free) < len(a.queue) {
+			a.free = append(a.free, b)
+		}
+		a.cond.Broadcast()
+	}
+}
+
+// Flush waits until the queued records are written and flushes the
+// underlying writer, see Logger.Flush. It returns the first error the
+// underlying writer returned since the last Flush.
+func (a *AsyncWriter) Flush() error {
+	a.mu.Lock()
+	for a.n > 0 || a.writing {
+		a.cond.Wait()
+	}
+	err := a.err
+	a.err = nil
+	w := a.w
+	a.mu.Unlock()
+
+	if ferr := flushWriter(w); err == nil {
+		err = ferr
+	}
+
+	return err
+}
+
+// Reopen waits until the queued records are written and reopens the
+// underlying writer, see Logger.Reopen. Writes wait while it reopens.
+func (a *AsyncWriter) Reopen() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for a.n > 0 || a.writing {
+		a.cond.Wait()
+	}
+
+	// the records before the rotation stay in the old file
+	if err := flushWriter(a.w); err != nil {
+		return err
+	}
+
+	w, err := reopenWriter(a.w)
+	a.w = w
+
+	return err
+}
+
+// Close writes the queued records and stops the background goroutine,
+// later writes return an error.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	a.closed = true
+	a.cond.Broadcast()
+	a.mu.Unlock()
+
+	<-a.done
+
+	return a.Flush()
+}
+
+// Dropped returns the number of records dropped because the queue was full.
+func (a *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
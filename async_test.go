@@ -0,0 +1,147 @@
+package cloudglog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+// gateWriter blocks its first Write until the gate is opened
+type gateWriter struct {
+	entered chan struct{}
+	gate    chan struct{}
+	once    sync.Once
+
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{entered: make(chan struct{}), gate: make(chan struct{})}
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	g.once.Do(func() {
+		close(g.entered)
+		<-g.gate
+	})
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.b.Write(p)
+}
+
+func (g *gateWriter) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.b.String()
+}
+
+// fill writes a and waits until it is being written, then writes rest
+func (g *gateWriter) fill(a *AsyncWriter, rest ...string) {
+	a.Write([]byte("a"))
+	<-g.entered
+	for _, s := range rest {
+		a.Write([]byte(s))
+	}
+}
+
+func Test_AsyncWriter(t *testing.T) {
+
+	var b syncBuffer
+	a := NewAsyncWriter(&b, 16, AsyncBlock)
+
+	l := New(a, DefaultFormat, NoColor, 0)
+	for i := 0; i < 100; i++ {
+		l.Infof("line %d", i)
+	}
+
+	assert.NoError(t, l.Flush())
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	assert.Len(t, lines, 100)
+	assert.True(t, strings.HasSuffix(lines[99], ": line 99"), "unexpected line %q", lines[99])
+	assert.Equal(t, uint64(0), a.Dropped())
+
+	assert.NoError(t, a.Close())
+	_, err := a.Write([]byte("closed"))
+	assert.Equal(t, errAsyncClosed, err)
+	assert.NoError(t, a.Close())
+}
+
+func Test_AsyncWriterDropNewest(t *testing.T) {
+
+	g := newGateWriter()
+	a := NewAsyncWriter(g, 2, AsyncDropNewest)
+	defer a.Close()
+
+	g.fill(a, "b", "c", "d", "e")
+	close(g.gate)
+
+	assert.NoError(t, a.Flush())
+	assert.Equal(t, "abc", g.String())
+	assert.Equal(t, uint64(2), a.Dropped())
+}
+
+func Test_AsyncWriterDropOldest(t *testing.T) {
+
+	g := newGateWriter()
+	a := NewAsyncWriter(g, 2, AsyncDropOldest)
+	defer a.Close()
+
+	g.fill(a, "b", "c", "d", "e")
+	close(g.gate)
+
+	assert.NoError(t, a.Flush())
+	assert.Equal(t, "ade", g.String())
+	assert.Equal(t, uint64(2), a.Dropped())
+}
+
+func Test_AsyncWriterBlock(t *testing.T) {
+
+	g := newGateWriter()
+	a := NewAsyncWriter(g, 2, AsyncBlock)
+	defer a.Close()
+
+	g.fill(a, "b", "c")
+
+	written := make(chan struct{})
+	go func() {
+		a.Write([]byte("d"))
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("Write did not block on a full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(g.gate)
+	<-written
+
+	assert.NoError(t, a.Flush())
+	assert.Equal(t, "abcd", g.String())
+	assert.Equal(t, uint64(0), a.Dropped())
+}
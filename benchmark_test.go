@@ -0,0 +1,104 @@
+package cloudglog
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+var benchFormats = []struct {
+	name   string
+	format formatStyle
+	colors []colorStyle
+}{
+	{"default", DefaultFormat, benchColors},
+	{"modern", ModernFormat, benchColors},
+	{"json", JSONFormat, []colorStyle{NoColor}},
+	{"cloud", CloudLoggingFormat, []colorStyle{NoColor}},
+}
+
+var benchColors = []colorStyle{
+	NoColor,
+	PrefixColor,
+	PrefixBoldColor,
+	FullColor,
+	FullBoldColor,
+	FullColorWithBoldMessage,
+	FullColorWithBoldPrefix,
+}
+
+// benchLoggers runs f with a Logger writing to ioutil.Discard for each
+// format and color style
+func benchLoggers(b *testing.B, f func(b *testing.B, l *Logger)) {
+	for _, bf := range benchFormats {
+		for _, c := range bf.colors {
+			l := New(ioutil.Discard, bf.format, c, 0)
+			b.Run(bf.name+"/"+c.String(), func(b *testing.B) {
+				b.ReportAllocs()
+				f(b, l)
+			})
+		}
+	}
+}
+
+func BenchmarkInfo(b *testing.B) {
+	benchLoggers(b, func(b *testing.B, l *Logger) {
+		for i := 0; i < b.N; i++ {
+			l.Info("the quick brown fox jumps over the lazy dog")
+		}
+	})
+}
+
+func BenchmarkInfof(b *testing.B) {
+	benchLoggers(b, func(b *testing.B, l *Logger) {
+		for i := 0; i < b.N; i++ {
+			l.Infof("request %s took %d ms", "GET /", 42)
+		}
+	})
+}
+
+func BenchmarkInfow(b *testing.B) {
+	benchLoggers(b, func(b *testing.B, l *Logger) {
+		l = l.With("service", "api")
+		for i := 0; i < b.N; i++ {
+			l.Infow("request done", "path", "/", "status", 200)
+		}
+	})
+}
+
+func BenchmarkParallel(b *testing.B) {
+	benchLoggers(b, func(b *testing.B, l *Logger) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				l.Info("the quick brown fox jumps over the lazy dog")
+			}
+		})
+	})
+}
+
+func BenchmarkDisabled(b *testing.B) {
+
+	l := New(ioutil.Discard, DefaultFormat, NoColor, 0)
+
+	b.Run("V", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.V(2).Infof("request %s took %d ms", "GET /", 42)
+		}
+	})
+
+	b.Run("Trace", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.Tracef("request %s took %d ms", "GET /", 42)
+		}
+	})
+
+	b.Run("VModule", func(b *testing.B) {
+		l := New(ioutil.Discard, DefaultFormat, NoColor, 0)
+		l.SetVModule("server=3,db/*=2")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.V(2).Info("not logged")
+		}
+	})
+}
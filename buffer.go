@@ -0,0 +1,55 @@
+package cloudglog
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// maxPooledBuffer is the capacity above which buffers are left to the
+// garbage collector instead of being pooled
+const maxPooledBuffer = 64 << 10
+
+// buffer is a pooled byte slice for messages and formatted records
+type buffer struct {
+	b []byte
+}
+
+var (
+	buffers = sync.Pool{New: func() interface{} { return &buffer{b: make([]byte, 0, 256)} }}
+	records = sync.Pool{New: func() interface{} { return new(Record) }}
+)
+
+// getBuffer returns an empty buffer from the pool
+func getBuffer() *buffer {
+	b := buffers.Get().(*buffer)
+	b.b = b.b[:0]
+	return b
+}
+
+// putBuffer returns b to the pool, b must not be used afterwards
+func putBuffer(b *buffer) {
+	if cap(b.b) > maxPooledBuffer {
+		return
+	}
+	buffers.Put(b)
+}
+
+// getRecord returns a zero Record from the pool
+func getRecord() *Record {
+	return records.Get().(*Record)
+}
+
+// putRecord clears r and returns it to the pool, r must not be used afterwards
+func putRecord(r *Record) {
+	*r = Record{}
+	records.Put(r)
+}
+
+// bytesToString returns b as a string without copying it, b must not
+// change while the string is in use
+func bytesToString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(&b[0], len(b))
+}
@@ -0,0 +1,215 @@
+package cloudglog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Keys of fields that CloudLoggingFormat writes as the special fields of
+// the Cloud Logging structured logging schema instead of jsonPayload members.
+const (
+	CloudTraceKey        = "logging.googleapis.com/trace"         // string: projects/PROJECT_ID/traces/TRACE_ID
+	CloudSpanIDKey       = "logging.googleapis.com/spanId"        // string: hex span id
+	CloudTraceSampledKey = "logging.googleapis.com/trace_sampled" // bool
+	CloudLabelsKey       = "logging.googleapis.com/labels"        // map[string]string, merged over all fields
+)
+
+// Keys of the fields that link a record to a trace, see cloudotel.
+// CloudLoggingFormat writes them as CloudTraceKey, CloudSpanIDKey and
+// CloudTraceSampledKey, the other formats as they are.
+const (
+	TraceIDKey      = "trace_id"      // string: hex trace id
+	SpanIDKey       = "span_id"       // string: hex span id
+	TraceSampledKey = "trace_sampled" // bool
+)
+
+// cloudProject is the project of SetCloudProject
+var cloudProject atomic.Value // string
+
+// SetCloudProject sets the Google Cloud project CloudLoggingFormat
+// qualifies the trace of a TraceIDKey field with, it defaults to the
+// GOOGLE_CLOUD_PROJECT environment variable. Without project the trace
+// id is written as it is.
+func SetCloudProject(projectID string) {
+	cloudProject.Store(projectID)
+}
+
+// cloudSeverities maps the logTypes to Cloud Logging severities
+var cloudSeverities = []string{
+	TRACE:   "DEBUG",
+	INFO:    "INFO",
+	WARNING: "WARNING",
+	ERROR:   "ERROR",
+	FATAL:   "CRITICAL",
+}
+
+// cloudKeys are written by appendCloud, fields with the same key are prefixed with "fields."
+var cloudKeys = map[string]bool{
+	"severity":                              true,
+	"message":                               true,
+	"time":                                  true,
+	"logging.googleapis.com/sourceLocation": true,
+	"stack_trace":                           true,
+	CloudLabelsKey:                          true, // a labels field that is no map[string]string
+}
+
+// CloudTrace returns the Field that links a record to the trace traceID of the
+// Google Cloud project projectID.
+func CloudTrace(projectID, traceID string) Field {
+	return Field{Key: CloudTraceKey, Value: "projects/" + projectID + "/traces/" + traceID}
+}
+
+// CloudSpanID returns the Field that links a record to the span spanID.
+func CloudSpanID(spanID string) Field {
+	return Field{Key: CloudSpanIDKey, Value: spanID}
+}
+
+// CloudLabels returns the Field that adds labels to a record, labels
+// of several fields are merged.
+func CloudLabels(labels map[string]string) Field {
+	return Field{Key: CloudLabelsKey, Value: labels}
+}
+
+// appendCloud appends the record as one Cloud Logging structured log entry
+// followed by a newline
+func (r *Record) appendCloud(buf []byte) []byte {
+
+	buf = append(buf, `{"severity":`...)
+	buf = appendJSONString(buf, cloudSeverities[r.Level])
+	buf = append(buf, `,"message":`...)
+	buf = appendJSONString(buf, strings.TrimSuffix(r.Message, "\n"))
+	buf = append(buf, `,"time":"`...)
+	buf = r.Time.UTC().AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, '"')
+
+	if file, line, function := r.Caller(); file != "???" {
+		buf = append(buf, `,"logging.googleapis.com/sourceLocation":{"file":`...)
+		buf = appendJSONString(buf, file)
+		buf = append(buf, `,"line":"`...)
+		buf = strconv.AppendInt(buf, int64(line), 10)
+		buf = append(buf, '"')
+		if function != "" {
+			buf = append(buf, `,"function":`...)
+			buf = appendJSONString(buf, function)
+		}
+		buf = append(buf, '}')
+	}
+
+	// Error Reporting picks up the goroutine dump from stack_trace
+	if len(r.Stack) > 0 {
+		buf = append(buf, `,"stack_trace":`...)
+		buf = appendJSONString(buf, string(r.Stack))
+	}
+
+	var labels map[string]string
+	fields := make([]Field, 0, len(r.Fields))
+
+	for i, f := range r.Fields {
+		if key := cloudTraceKey(f); key != "" {
+			// a later field links the record to another trace or span
+			if hasCloudTraceKey(r.Fields[i+1:], key) {
+				continue
+			}
+			buf = append(buf, ',')
+			buf = appendJSONString(buf, key)
+			buf = append(buf, ':')
+			if f.Key == TraceIDKey {
+				buf = appendJSONString(buf, cloudTraceName(f.Value.(string)))
+			} else {
+				buf = appendJSONValue(buf, f.Value)
+			}
+			continue
+		}
+
+		switch f.Key {
+		case CloudLabelsKey:
+			l, ok := f.Value.(map[string]string)
+			if !ok {
+				fields = append(fields, f)
+				continue
+			}
+			if labels == nil {
+				labels = make(map[string]string, len(l))
+			}
+			for k, v := range l {
+				labels[k] = v
+			}
+		default:
+			fields = append(fields, f)
+		}
+	}
+
+	if len(labels) > 0 {
+		buf = append(buf, `,"logging.googleapis.com/labels":{`...)
+		buf = appendLabels(buf, labels)
+		buf = append(buf, '}')
+	}
+
+	buf = appendJSONFields(buf, fields, cloudKeys)
+	buf = append(buf, "}\n"...)
+
+	return buf
+}
+
+// cloudTraceKey returns the special Cloud Logging key f is written as,
+// "" if it is none of the trace fields
+func cloudTraceKey(f Field) string {
+
+	switch f.Key {
+	case CloudTraceKey, CloudSpanIDKey, CloudTraceSampledKey:
+		return f.Key
+	case TraceIDKey:
+		// only ids can be qualified with the project
+		if _, ok := f.Value.(string); ok {
+			return CloudTraceKey
+		}
+	case SpanIDKey:
+		return CloudSpanIDKey
+	case TraceSampledKey:
+		return CloudTraceSampledKey
+	}
+
+	return ""
+}
+
+// hasCloudTraceKey reports whether one of fields is written as the trace key
+func hasCloudTraceKey(fields []Field, key string) bool {
+	for _, f := range fields {
+		if cloudTraceKey(f) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// cloudTraceName qualifies a trace id with the project of SetCloudProject
+func cloudTraceName(id string) string {
+	if project, _ := cloudProject.Load().(string); project != "" {
+		return "projects/" + project + "/traces/" + id
+	}
+	return id
+}
+
+// appendLabels appends labels sorted by key as JSON members
+func appendLabels(buf []byte, labels map[string]string) []byte {
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendJSONString(buf, k)
+		buf = append(buf, ':')
+		buf = appendJSONString(buf, labels[k])
+	}
+
+	return buf
+}
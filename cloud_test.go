@@ -0,0 +1,97 @@
+package cloudglog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CloudLoggingFormat(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, CloudLoggingFormat, NoColor, 0)
+
+	l.With(CloudTrace("proj", "abc"), CloudSpanID("0102"), CloudLabels(map[string]string{"a": "1"})).
+		Errorw("failed", CloudLabels(map[string]string{"b": "2"}), "time", "dup", "user", "x")
+
+	var obj map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &obj), "invalid json %q", b.String())
+
+	assert.Equal(t, "ERROR", obj["severity"])
+	assert.Equal(t, "failed", obj["message"])
+	assert.Equal(t, "projects/proj/traces/abc", obj[CloudTraceKey])
+	assert.Equal(t, "0102", obj[CloudSpanIDKey])
+	assert.Equal(t, map[string]interface{}{"a": "1", "b": "2"}, obj[CloudLabelsKey])
+	assert.Equal(t, "dup", obj["fields.time"])
+	assert.Equal(t, "x", obj["user"])
+
+	loc, ok := obj["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	assert.True(t, ok, "missing sourceLocation in %q", b.String())
+	assert.Contains(t, loc["file"], "cloud_test.go")
+}
+
+func Test_CloudSeverities(t *testing.T) {
+
+	assert.Equal(t, numLogTypes, len(cloudSeverities))
+	assert.Equal(t, "DEBUG", cloudSeverities[TRACE])
+	assert.Equal(t, "CRITICAL", cloudSeverities[FATAL])
+}
+
+func Test_CloudTraceFields(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, CloudLoggingFormat, NoColor, 0)
+
+	l.Infow("traced", TraceIDKey, "abc", SpanIDKey, "0102", TraceSampledKey, false)
+
+	var obj map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &obj), "invalid json %q", b.String())
+	assert.Equal(t, "abc", obj[CloudTraceKey])
+	assert.Equal(t, "0102", obj[CloudSpanIDKey])
+	assert.Equal(t, false, obj[CloudTraceSampledKey])
+	assert.NotContains(t, obj, TraceIDKey)
+
+	SetCloudProject("proj")
+	defer SetCloudProject("")
+
+	b.Reset()
+	l.Infow("traced", TraceIDKey, "abc")
+	obj = nil
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &obj), "invalid json %q", b.String())
+	assert.Equal(t, "projects/proj/traces/abc", obj[CloudTraceKey])
+}
+
+func Test_CloudDuplicateKeys(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, CloudLoggingFormat, NoColor, 0).With(CloudTrace("proj", "old"), CloudSpanID("01"), "user", "a")
+
+	l.Infow("traced", TraceIDKey, "new", SpanIDKey, "02", "user", "b")
+
+	for _, key := range []string{CloudTraceKey, CloudSpanIDKey, `"user"`} {
+		assert.Equal(t, 1, bytes.Count(b.Bytes(), []byte(key)), "unexpected output %q", b.String())
+	}
+
+	var obj map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &obj), "invalid json %q", b.String())
+	assert.Equal(t, "new", obj[CloudTraceKey])
+	assert.Equal(t, "02", obj[CloudSpanIDKey])
+	assert.Equal(t, "b", obj["user"])
+}
+
+func Test_CloudLabelsNotMap(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, CloudLoggingFormat, NoColor, 0)
+
+	// a labels field that is no map is renamed like the other reserved keys
+	l.Infow("labeled", CloudLabels(map[string]string{"a": "1"}), CloudLabelsKey, "b=2")
+	assert.Equal(t, 1, bytes.Count(b.Bytes(), []byte(`"`+CloudLabelsKey+`"`)), "unexpected output %q", b.String())
+
+	var obj map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &obj), "invalid json %q", b.String())
+	assert.Equal(t, map[string]interface{}{"a": "1"}, obj[CloudLabelsKey])
+	assert.Equal(t, "b=2", obj["fields."+CloudLabelsKey])
+}
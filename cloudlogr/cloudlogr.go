@@ -0,0 +1,111 @@
+// Package cloudlogr provides a logr.LogSink that logs through a cloudglog.Logger,
+// so libraries that log with go-logr, like controller-runtime and client-go,
+// write in the format of the rest of the program.
+//
+// logr V-levels map onto the V() level of the Logger, including vmodule,
+// errors go to the ERROR log, WithValues adds fields and the names of
+// WithName are joined by "/" into the "logger" field.
+//
+// Example:
+//
+//	ctrl.SetLogger(cloudlogr.New(cloudglog.Default()))
+package cloudlogr
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/morriswinkler/cloudglog"
+)
+
+// NameKey is the field the logger name is written to
+const NameKey = "logger"
+
+// ErrorKey is the field the error of Error is written to
+const ErrorKey = "error"
+
+// New returns a logr.Logger that logs through l.
+func New(l *cloudglog.Logger) logr.Logger {
+	return logr.New(NewSink(l))
+}
+
+// sink implements logr.LogSink and logr.CallDepthLogSink
+type sink struct {
+	l      *cloudglog.Logger
+	name   string
+	values []interface{}
+	depth  int // frames between the caller and the methods of sink
+}
+
+// NewSink returns a logr.LogSink that logs through l.
+func NewSink(l *cloudglog.Logger) logr.LogSink {
+	return &sink{l: l}
+}
+
+// Init takes the call depth of the logr.Logger.
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.depth += info.CallDepth
+}
+
+// Enabled reports whether the V() level of the Logger is at least level.
+func (s *sink) Enabled(level int) bool {
+	// Enabled is called by a logr.Logger method, one frame more than Info
+	return s.l.VDepth(s.depth+1, level).Enabled()
+}
+
+// Info logs msg and the key/value pairs to the INFO log.
+func (s *sink) Info(level int, msg string, kv ...interface{}) {
+	s.l.With(s.fields(nil, kv)...).InfoDepth(s.depth+1, msg)
+}
+
+// Error logs err, msg and the key/value pairs to the ERROR log.
+func (s *sink) Error(err error, msg string, kv ...interface{}) {
+	s.l.With(s.fields(err, kv)...).ErrorDepth(s.depth+1, msg)
+}
+
+// WithValues returns a sink that adds the key/value pairs to every record.
+func (s *sink) WithValues(kv ...interface{}) logr.LogSink {
+	s2 := *s
+	s2.values = append(s.values[:len(s.values):len(s.values)], kv...)
+	return &s2
+}
+
+// WithName returns a sink that appends name to the logger name.
+func (s *sink) WithName(name string) logr.LogSink {
+	s2 := *s
+	if s.name != "" {
+		name = s.name + "/" + name
+	}
+	s2.name = name
+	return &s2
+}
+
+// WithCallDepth returns a sink that skips depth more frames to find the caller.
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	s2 := *s
+	s2.depth += depth
+	return &s2
+}
+
+// fields returns the key/value pairs of a record: the logger name, the
+// error, the values of WithValues and kv. logr.Marshaler values are
+// replaced by what they marshal to.
+func (s *sink) fields(err error, kv []interface{}) []interface{} {
+
+	fields := make([]interface{}, 0, 4+len(s.values)+len(kv))
+
+	if s.name != "" {
+		fields = append(fields, NameKey, s.name)
+	}
+	if err != nil {
+		fields = append(fields, ErrorKey, err)
+	}
+	fields = append(fields, s.values...)
+	fields = append(fields, kv...)
+
+	for i := 1; i < len(fields); i += 2 {
+		if m, ok := fields[i].(logr.Marshaler); ok {
+			fields[i] = m.MarshalLog()
+		}
+	}
+
+	return fields
+}
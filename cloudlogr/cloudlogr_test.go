@@ -0,0 +1,64 @@
+package cloudlogr
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/morriswinkler/cloudglog"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	name, password string
+}
+
+func (u user) MarshalLog() interface{} {
+	return u.name
+}
+
+func Test_Sink(t *testing.T) {
+
+	var b bytes.Buffer
+	l := cloudglog.New(&b, cloudglog.DefaultFormat, cloudglog.NoColor, 1)
+	log := New(l).WithName("controller").WithName("pods").WithValues("ns", "default")
+
+	log.Info("reconciled", "pod", "web-0", "user", user{"bob", "secret"})
+	assert.True(t, strings.HasPrefix(b.String(), "INFO: "), "unexpected output %q", b.String())
+	assert.Contains(t, b.String(), "cloudlogr_test.go:")
+	assert.True(t, strings.HasSuffix(b.String(), ": reconciled logger=controller/pods ns=default pod=web-0 user=bob\n"), "unexpected output %q", b.String())
+
+	b.Reset()
+	log.V(1).Info("shown")
+	log.V(2).Info("hidden")
+	assert.Contains(t, b.String(), "shown")
+	assert.NotContains(t, b.String(), "hidden")
+
+	b.Reset()
+	log.V(2).Error(errors.New("boom"), "failed", "pod", "web-1")
+	assert.True(t, strings.HasPrefix(b.String(), "ERROR: "), "unexpected output %q", b.String())
+	assert.Contains(t, b.String(), "cloudlogr_test.go:")
+	assert.True(t, strings.HasSuffix(b.String(), ": failed logger=controller/pods error=boom ns=default pod=web-1\n"), "unexpected output %q", b.String())
+}
+
+func Test_SinkVModule(t *testing.T) {
+
+	var b bytes.Buffer
+	l := cloudglog.New(&b, cloudglog.DefaultFormat, cloudglog.NoColor, 0)
+	assert.NoError(t, l.SetVModule("cloudlogr_test=3"))
+
+	log := New(l)
+	assert.True(t, log.V(3).Enabled())
+	assert.False(t, log.V(4).Enabled())
+
+	// a helper that logs for its caller
+	helper := func(msg string) {
+		log.WithCallDepth(1).Info(msg)
+	}
+	helper("from helper")
+	_, _, line, _ := runtime.Caller(0)
+	assert.Contains(t, b.String(), "cloudlogr_test.go:"+strconv.Itoa(line-1)+": from helper")
+}
@@ -0,0 +1,135 @@
+// Package cloudlogrus forwards logrus entries to a cloudglog.Logger, so
+// dependencies that log with logrus write in the format and color style of
+// the rest of the program.
+//
+// A Hook writes the entries to the outputs of the Logger, the logrus output
+// is then usually discarded. A Formatter renders the entries with the format
+// of the Logger and leaves the writing to logrus.
+//
+// logrus levels map onto the severity logs: Trace and Debug go to the TRACE
+// log and are only written while it is enabled, Info to INFO, Warn to
+// WARNING, Error to ERROR, Fatal and Panic to FATAL. logrus itself exits or
+// panics after the entry is written. The data of an entry becomes fields
+// sorted by key. The caller is taken from the entry if ReportCaller is set
+// and is looked up past the logrus frames otherwise.
+//
+// Example:
+//
+//	logrus.SetOutput(io.Discard)
+//	logrus.AddHook(cloudlogrus.NewHook(cloudglog.Default()))
+package cloudlogrus
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/morriswinkler/cloudglog"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a logrus.Hook that writes entries to a Logger.
+type Hook struct {
+	l *cloudglog.Logger
+}
+
+// NewHook returns a Hook that writes to l.
+func NewHook(l *cloudglog.Logger) *Hook {
+	return &Hook{l: l}
+}
+
+// Levels returns all logrus levels.
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire writes e to the Logger.
+func (h *Hook) Fire(e *logrus.Entry) error {
+	if e.Level >= logrus.DebugLevel && !h.l.TraceEnabled() {
+		return nil
+	}
+	h.l.LogRecord(record(e))
+	return nil
+}
+
+// Formatter is a logrus.Formatter that renders entries with the format
+// and color style of a Logger.
+type Formatter struct {
+	l *cloudglog.Logger
+}
+
+// NewFormatter returns a Formatter that renders like l.
+func NewFormatter(l *cloudglog.Logger) *Formatter {
+	return &Formatter{l: l}
+}
+
+// Format renders e, entries of the TRACE log are rendered whether it is
+// enabled or not, logrus filters them by its own level.
+func (f *Formatter) Format(e *logrus.Entry) ([]byte, error) {
+	return f.l.AppendRecord(nil, record(e)), nil
+}
+
+// record converts e to a cloudglog.Record
+func record(e *logrus.Entry) cloudglog.Record {
+
+	r := cloudglog.Record{
+		Time:    e.Time,
+		Message: e.Message,
+	}
+
+	switch e.Level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		r.Level = cloudglog.FATAL
+	case logrus.ErrorLevel:
+		r.Level = cloudglog.ERROR
+	case logrus.WarnLevel:
+		r.Level = cloudglog.WARNING
+	case logrus.InfoLevel:
+		r.Level = cloudglog.INFO
+	default:
+		r.Level = cloudglog.TRACE
+	}
+
+	if e.Caller != nil {
+		r.SetCaller(e.Caller.File, e.Caller.Line, e.Caller.Function)
+	} else if f, ok := caller(); ok {
+		r.SetCaller(f.File, f.Line, f.Function)
+	}
+
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	r.Fields = make([]cloudglog.Field, 0, len(keys))
+	for _, k := range keys {
+		r.Fields = append(r.Fields, cloudglog.Field{Key: k, Value: e.Data[k]})
+	}
+
+	return r
+}
+
+// caller returns the first frame outside of logrus and this package
+func caller() (runtime.Frame, bool) {
+
+	var pcs [32]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		f, more := frames.Next()
+		if !internal(f.Function) {
+			return f, true
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+// internal reports whether function belongs to logrus or this package
+func internal(function string) bool {
+	return strings.HasPrefix(function, "github.com/sirupsen/logrus.") ||
+		strings.HasPrefix(function, "github.com/morriswinkler/cloudglog/cloudlogrus.")
+}
@@ -0,0 +1,79 @@
+package cloudlogrus_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/morriswinkler/cloudglog"
+	"github.com/morriswinkler/cloudglog/cloudlogrus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Hook(t *testing.T) {
+
+	var b bytes.Buffer
+	l := cloudglog.New(&b, cloudglog.DefaultFormat, cloudglog.NoColor, 0)
+
+	lr := logrus.New()
+	lr.SetOutput(io.Discard)
+	lr.SetLevel(logrus.TraceLevel)
+	lr.AddHook(cloudlogrus.NewHook(l))
+
+	lr.WithFields(logrus.Fields{"port": 5432, "db": "users"}).Info("connected")
+	assert.True(t, strings.HasPrefix(b.String(), "INFO: "), "unexpected output %q", b.String())
+	assert.Contains(t, b.String(), "cloudlogrus_test.go:")
+	assert.True(t, strings.HasSuffix(b.String(), ": connected db=users port=5432\n"), "unexpected output %q", b.String())
+
+	b.Reset()
+	lr.Warn("slow")
+	assert.True(t, strings.HasPrefix(b.String(), "WARNING: "), "unexpected output %q", b.String())
+	assert.Contains(t, b.String(), "cloudlogrus_test.go:")
+
+	b.Reset()
+	lr.WithError(errors.New("boom")).Error("failed")
+	assert.True(t, strings.HasPrefix(b.String(), "ERROR: "), "unexpected output %q", b.String())
+	assert.True(t, strings.HasSuffix(b.String(), ": failed error=boom\n"), "unexpected output %q", b.String())
+
+	// Debug and Trace go to the TRACE log, written only while it is enabled
+	b.Reset()
+	lr.Debug("hidden")
+	lr.Trace("hidden")
+	assert.Empty(t, b.String())
+
+	l.EnableTrace(true)
+	lr.Debug("shown")
+	assert.True(t, strings.HasPrefix(b.String(), "TRACE: "), "unexpected output %q", b.String())
+}
+
+func Test_HookReportCaller(t *testing.T) {
+
+	var b bytes.Buffer
+	l := cloudglog.New(&b, cloudglog.ModernFormat, cloudglog.NoColor, 0)
+
+	lr := logrus.New()
+	lr.SetOutput(io.Discard)
+	lr.SetReportCaller(true)
+	lr.AddHook(cloudlogrus.NewHook(l))
+
+	lr.Info("with caller")
+	assert.Contains(t, b.String(), "[cloudlogrus][cloudlogrus_test.go][:")
+}
+
+func Test_Formatter(t *testing.T) {
+
+	var b bytes.Buffer
+	l := cloudglog.New(io.Discard, cloudglog.DefaultFormat, cloudglog.NoColor, 0).With("app", "api")
+
+	lr := logrus.New()
+	lr.SetOutput(&b)
+	lr.SetFormatter(cloudlogrus.NewFormatter(l))
+
+	lr.WithField("version", "1.2").Error("started")
+	assert.True(t, strings.HasPrefix(b.String(), "ERROR: "), "unexpected output %q", b.String())
+	assert.Contains(t, b.String(), "cloudlogrus_test.go:")
+	assert.True(t, strings.HasSuffix(b.String(), ": started app=api version=1.2\n"), "unexpected output %q", b.String())
+}
@@ -0,0 +1,51 @@
+// Package cloudotel links cloudglog records to the active OpenTelemetry span
+// of a context, so the log lines of a request show up with its trace.
+//
+// The trace id, span id and sampled flag of the span are added as the
+// cloudglog.TraceIDKey, SpanIDKey and TraceSampledKey fields. Text formats
+// render them as key=value pairs, JSONFormat as members and
+// CloudLoggingFormat as the logging.googleapis.com/trace, spanId and
+// trace_sampled keys that Cloud Trace correlates, see cloudglog.SetCloudProject.
+//
+// Example:
+//
+//	cloudotel.Register()
+//	ctx, span := tracer.Start(ctx, "checkout")
+//	cloudglog.InfofCtx(ctx, "charged %d", cents) // ... trace_id=4bf9... span_id=00f0... trace_sampled=true
+package cloudotel
+
+import (
+	"context"
+	"sync"
+
+	"github.com/morriswinkler/cloudglog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var register sync.Once
+
+// Register adds the fields of the span of the context to every record of
+// the cloudglog Ctx functions and SlogHandler, see
+// cloudglog.RegisterContextFields. Calling it more than once has no
+// further effect.
+func Register() {
+	register.Do(func() {
+		cloudglog.RegisterContextFields(Fields)
+	})
+}
+
+// Fields returns the trace id, span id and sampled flag of the span of
+// ctx, nil if ctx carries no valid span context.
+func Fields(ctx context.Context) []cloudglog.Field {
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return []cloudglog.Field{
+		{Key: cloudglog.TraceIDKey, Value: sc.TraceID().String()},
+		{Key: cloudglog.SpanIDKey, Value: sc.SpanID().String()},
+		{Key: cloudglog.TraceSampledKey, Value: sc.IsSampled()},
+	}
+}
@@ -0,0 +1,90 @@
+package cloudotel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/morriswinkler/cloudglog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanContext returns ctx with a sampled remote span
+func spanContext(t *testing.T) context.Context {
+
+	tid, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.NoError(t, err)
+	sid, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	assert.NoError(t, err)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+
+	return trace.ContextWithRemoteSpanContext(context.Background(), sc)
+}
+
+func Test_Fields(t *testing.T) {
+
+	assert.Nil(t, Fields(context.Background()))
+
+	assert.Equal(t, []cloudglog.Field{
+		{Key: cloudglog.TraceIDKey, Value: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{Key: cloudglog.SpanIDKey, Value: "00f067aa0ba902b7"},
+		{Key: cloudglog.TraceSampledKey, Value: true},
+	}, Fields(spanContext(t)))
+}
+
+func Test_Register(t *testing.T) {
+
+	Register()
+	Register()
+
+	var b bytes.Buffer
+	l := cloudglog.New(&b, cloudglog.DefaultFormat, cloudglog.NoColor, 0)
+	ctx := l.NewContext(spanContext(t), "req", "8f2b")
+
+	cloudglog.InfofCtx(ctx, "charged %d", 42)
+	assert.True(t, strings.HasSuffix(b.String(), ": charged 42 req=8f2b trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_sampled=true\n"), "unexpected output %q", b.String())
+
+	// no span, no fields
+	b.Reset()
+	cloudglog.InfoCtx(l.NewContext(context.Background()), "idle")
+	assert.True(t, strings.HasSuffix(b.String(), ": idle\n"), "unexpected output %q", b.String())
+
+	// JSON writes the fields as members
+	b.Reset()
+	l.FormatStyle(cloudglog.JSONFormat)
+	cloudglog.InfoCtx(ctx, "json")
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &m))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", m["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", m["span_id"])
+	assert.Equal(t, true, m["trace_sampled"])
+
+	// Cloud Logging writes the keys Cloud Trace correlates
+	b.Reset()
+	l.FormatStyle(cloudglog.CloudLoggingFormat)
+	cloudglog.SetCloudProject("shop")
+	defer cloudglog.SetCloudProject("")
+	cloudglog.InfoCtx(ctx, "cloud")
+	m = nil
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &m))
+	assert.Equal(t, "projects/shop/traces/4bf92f3577b34da6a3ce929d0e0e4736", m["logging.googleapis.com/trace"])
+	assert.Equal(t, "00f067aa0ba902b7", m["logging.googleapis.com/spanId"])
+	assert.Equal(t, true, m["logging.googleapis.com/trace_sampled"])
+	assert.NotContains(t, m, "trace_id")
+
+	// slog records of a SlogHandler get the fields from the context
+	b.Reset()
+	l.FormatStyle(cloudglog.DefaultFormat)
+	slog.New(cloudglog.NewSlogHandler(l, nil)).InfoContext(ctx, "slog", "items", 3)
+	assert.True(t, strings.HasSuffix(b.String(), ": slog trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_sampled=true items=3\n"), "unexpected output %q", b.String())
+}
@@ -0,0 +1,133 @@
+// Package cloudzap provides a zapcore.Core that writes zap entries through a
+// cloudglog.Logger, so dependencies that log with zap write in the format and
+// color style of the rest of the program.
+//
+// zap levels map onto the severity logs: Debug goes to the TRACE log and is
+// only written while it is enabled, Info to INFO, Warn to WARNING, Error and
+// DPanic to ERROR, Panic and Fatal to FATAL. zap itself panics or exits after
+// the entry is written. The caller of the entry, the logger name and the
+// fields are kept, fields of namespaces are qualified with the namespace
+// names separated by dots.
+//
+// Example:
+//
+//	z := zap.New(cloudzap.NewCore(cloudglog.Default(), zapcore.DebugLevel), zap.AddCaller())
+package cloudzap
+
+import (
+	"github.com/morriswinkler/cloudglog"
+	"go.uber.org/zap/zapcore"
+)
+
+// NameKey is the field the logger name is written to
+const NameKey = "logger"
+
+// core implements zapcore.Core
+type core struct {
+	l      *cloudglog.Logger
+	enab   zapcore.LevelEnabler
+	fields []cloudglog.Field // of With, their keys are qualified
+	prefix string            // the namespaces joined by dots, with a trailing dot
+}
+
+// NewCore returns a zapcore.Core that writes the entries enabled by enab
+// to l.
+func NewCore(l *cloudglog.Logger, enab zapcore.LevelEnabler) zapcore.Core {
+	return &core{l: l, enab: enab}
+}
+
+// setLevel sets the log of r for a zap level
+func setLevel(r *cloudglog.Record, level zapcore.Level) {
+	switch {
+	case level >= zapcore.PanicLevel:
+		r.Level = cloudglog.FATAL
+	case level >= zapcore.ErrorLevel:
+		r.Level = cloudglog.ERROR
+	case level >= zapcore.WarnLevel:
+		r.Level = cloudglog.WARNING
+	case level >= zapcore.InfoLevel:
+		r.Level = cloudglog.INFO
+	default:
+		r.Level = cloudglog.TRACE
+	}
+}
+
+// Enabled reports whether entries at level are written.
+func (c *core) Enabled(level zapcore.Level) bool {
+	if !c.enab.Enabled(level) {
+		return false
+	}
+	return level >= zapcore.InfoLevel || c.l.TraceEnabled()
+}
+
+// With returns a core that adds fields to every entry.
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	c2 := *c
+	c2.fields, c2.prefix = appendFields(c.fields[:len(c.fields):len(c.fields)], c.prefix, fields)
+	return &c2
+}
+
+// Check adds c to ce if the entry is enabled.
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write writes the entry and fields to the Logger.
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+
+	r := cloudglog.Record{
+		Time:    ent.Time,
+		Message: ent.Message,
+	}
+	setLevel(&r, ent.Level)
+
+	if ent.Caller.Defined {
+		r.SetCaller(ent.Caller.File, ent.Caller.Line, ent.Caller.Function)
+	}
+
+	r.Fields = c.fields[:len(c.fields):len(c.fields)]
+	if ent.LoggerName != "" {
+		r.Fields = append(r.Fields, cloudglog.Field{Key: NameKey, Value: ent.LoggerName})
+	}
+	r.Fields, _ = appendFields(r.Fields, c.prefix, fields)
+
+	if ent.Stack != "" {
+		r.Stack = []byte(ent.Stack)
+	}
+
+	c.l.LogRecord(r)
+
+	return nil
+}
+
+// Sync flushes the outputs of the Logger.
+func (c *core) Sync() error {
+	return c.l.Flush()
+}
+
+// appendFields appends the zap fields to fs, a namespace qualifies the
+// keys of the fields that follow it. It returns the extended prefix.
+func appendFields(fs []cloudglog.Field, prefix string, fields []zapcore.Field) ([]cloudglog.Field, string) {
+
+	for _, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			prefix += f.Key + "."
+			continue
+		}
+		if f.Type == zapcore.SkipType {
+			continue
+		}
+
+		// encode each field on its own to keep the order of the fields
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		for k, v := range enc.Fields {
+			fs = append(fs, cloudglog.Field{Key: prefix + k, Value: v})
+		}
+	}
+
+	return fs, prefix
+}
@@ -0,0 +1,80 @@
+package cloudzap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/morriswinkler/cloudglog"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func Test_Core(t *testing.T) {
+
+	var b bytes.Buffer
+	l := cloudglog.New(&b, cloudglog.DefaultFormat, cloudglog.NoColor, 0)
+	z := zap.New(NewCore(l, zapcore.DebugLevel), zap.AddCaller()).Named("db").With(zap.String("ns", "default"))
+
+	z.Info("connected", zap.Int("port", 5432), zap.Bool("tls", true))
+	assert.True(t, strings.HasPrefix(b.String(), "INFO: "), "unexpected output %q", b.String())
+	assert.Contains(t, b.String(), "cloudzap_test.go:")
+	assert.True(t, strings.HasSuffix(b.String(), ": connected ns=default logger=db port=5432 tls=true\n"), "unexpected output %q", b.String())
+
+	b.Reset()
+	z.Warn("slow")
+	assert.True(t, strings.HasPrefix(b.String(), "WARNING: "), "unexpected output %q", b.String())
+
+	b.Reset()
+	z.Error("failed", zap.Error(errors.New("boom")))
+	assert.True(t, strings.HasPrefix(b.String(), "ERROR: "), "unexpected output %q", b.String())
+	assert.Contains(t, b.String(), " error=boom")
+
+	// Debug goes to the TRACE log, written only while it is enabled
+	b.Reset()
+	z.Debug("hidden")
+	assert.Empty(t, b.String())
+
+	l.EnableTrace(true)
+	z.Debug("shown")
+	assert.True(t, strings.HasPrefix(b.String(), "TRACE: "), "unexpected output %q", b.String())
+
+	// the enabler of the core filters as well
+	b.Reset()
+	z = zap.New(NewCore(l, zapcore.WarnLevel))
+	z.Info("filtered")
+	assert.Empty(t, b.String())
+}
+
+func Test_CoreNamespace(t *testing.T) {
+
+	var b bytes.Buffer
+	l := cloudglog.New(&b, cloudglog.JSONFormat, cloudglog.NoColor, 0)
+	z := zap.New(NewCore(l, zapcore.InfoLevel)).With(zap.Namespace("req"), zap.String("id", "42"))
+
+	z.Info("handled", zap.Int("status", 200), zap.Skip())
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &m))
+	assert.Equal(t, "handled", m["message"])
+	assert.Equal(t, "42", m["req.id"])
+	assert.Equal(t, float64(200), m["req.status"])
+}
+
+func Test_CoreWithLoggerFields(t *testing.T) {
+
+	var b bytes.Buffer
+	l := cloudglog.New(&b, cloudglog.DefaultFormat, cloudglog.NoColor, 0).With("app", "api")
+	z := zap.New(NewCore(l, zapcore.InfoLevel))
+
+	z.Info("started", zap.String("version", "1.2"))
+	assert.True(t, strings.HasSuffix(b.String(), ": started app=api version=1.2\n"), "unexpected output %q", b.String())
+
+	// entries without caller are written with an unknown file
+	assert.Contains(t, b.String(), " ???:0: ")
+
+	assert.NoError(t, z.Sync())
+}
@@ -0,0 +1,234 @@
+package cloudglog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// ctxKey is the context key of the Logger of NewContext
+type ctxKey struct{}
+
+var (
+	contextFuncsMu sync.Mutex
+	contextFuncs   atomic.Value // []*contextFunc, copied on write
+)
+
+// contextFunc is a function of RegisterContextFields, its address
+// identifies it for unregistering
+type contextFunc struct {
+	f func(context.Context) []Field
+}
+
+// RegisterContextFields registers f to return fields taken from the
+// context of every Ctx call and every record of a SlogHandler, like the
+// trace and span of cloudotel. Fields of f come after the fields of the
+// Logger. unregister removes f again, calling it more than once has no
+// further effect.
+func RegisterContextFields(f func(ctx context.Context) []Field) (unregister func()) {
+	contextFuncsMu.Lock()
+	defer contextFuncsMu.Unlock()
+
+	cf := &contextFunc{f}
+	funcs, _ := contextFuncs.Load().([]*contextFunc)
+	contextFuncs.Store(append(funcs[:len(funcs):len(funcs)], cf))
+
+	return func() {
+		contextFuncsMu.Lock()
+		defer contextFuncsMu.Unlock()
+
+		funcs, _ := contextFuncs.Load().([]*contextFunc)
+		kept := make([]*contextFunc, 0, len(funcs))
+		for _, g := range funcs {
+			if g != cf {
+				kept = append(kept, g)
+			}
+		}
+		contextFuncs.Store(kept)
+	}
+}
+
+// contextFields appends the fields of the registered context functions to fields
+func contextFields(ctx context.Context, fields []Field) []Field {
+	funcs, _ := contextFuncs.Load().([]*contextFunc)
+	for _, cf := range funcs {
+		fields = append(fields, cf.f(ctx)...)
+	}
+	return fields
+}
+
+// ctxLogger returns the Logger of ctx with the fields of the registered
+// context functions added
+func ctxLogger(ctx context.Context) *Logger {
+	return FromContext(ctx).withContext(ctx)
+}
+
+// withContext returns l with the fields of the registered context
+// functions for ctx added
+func (l *Logger) withContext(ctx context.Context) *Logger {
+	if fields := contextFields(ctx, nil); len(fields) > 0 {
+		l = l.withFields(fields)
+	}
+	return l
+}
+
+// NewContext returns a copy of ctx that carries l with the key/value pairs
+// in kv added to every record, see With.
+func (l *Logger) NewContext(ctx context.Context, kv ...interface{}) context.Context {
+	if len(kv) > 0 {
+		l = l.With(kv...)
+	}
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// NewContext returns a copy of ctx that carries the Logger of ctx with the
+// key/value pairs in kv added, the default Logger if ctx carries none.
+// Fields attached at the edge of a request end up on every record logged
+// with the Ctx functions or the Logger of FromContext further down.
+//
+// Example:
+//
+//	ctx = cloudglog.NewContext(ctx, "req", rid, "tenant", tenant)
+//	cloudglog.InfofCtx(ctx, "%d items", n) // ... 12 items req=8f2b tenant=acme
+func NewContext(ctx context.Context, kv ...interface{}) context.Context {
+	return FromContext(ctx).NewContext(ctx, kv...)
+}
+
+// FromContext returns the Logger carried by ctx, the default Logger if it
+// carries none.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return l
+	}
+	return std
+}
+
+// TraceCtx logs to the TRACE log of the Logger of ctx.
+// Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
+func TraceCtx(ctx context.Context, args ...interface{}) {
+	if FromContext(ctx).TraceEnabled() {
+		ctxLogger(ctx).outputPrint(TRACE, CallDepth, args)
+	}
+}
+
+// TracefCtx logs to the TRACE log of the Logger of ctx.
+// Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
+func TracefCtx(ctx context.Context, format string, args ...interface{}) {
+	if FromContext(ctx).TraceEnabled() {
+		ctxLogger(ctx).outputPrintf(TRACE, CallDepth, format, args)
+	}
+}
+
+// InfoCtx logs to the INFO log of the Logger of ctx.
+// Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
+func InfoCtx(ctx context.Context, args ...interface{}) {
+	ctxLogger(ctx).outputPrint(INFO, CallDepth, args)
+}
+
+// InfofCtx logs to the INFO log of the Logger of ctx.
+// Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
+func InfofCtx(ctx context.Context, format string, args ...interface{}) {
+	ctxLogger(ctx).outputPrintf(INFO, CallDepth, format, args)
+}
+
+// InfowCtx logs msg and the key/value pairs in kv to the INFO log of the Logger of ctx.
+func InfowCtx(ctx context.Context, msg string, kv ...interface{}) {
+	ctxLogger(ctx).output(INFO, CallDepth, msg, makeFields(kv)...)
+}
+
+// WarningCtx logs to the WARNING log of the Logger of ctx.
+// Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
+func WarningCtx(ctx context.Context, args ...interface{}) {
+	ctxLogger(ctx).outputPrint(WARNING, CallDepth, args)
+}
+
+// WarningfCtx logs to the WARNING log of the Logger of ctx.
+// Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
+func WarningfCtx(ctx context.Context, format string, args ...interface{}) {
+	ctxLogger(ctx).outputPrintf(WARNING, CallDepth, format, args)
+}
+
+// WarningwCtx logs msg and the key/value pairs in kv to the WARNING log of the Logger of ctx.
+func WarningwCtx(ctx context.Context, msg string, kv ...interface{}) {
+	ctxLogger(ctx).output(WARNING, CallDepth, msg, makeFields(kv)...)
+}
+
+// ErrorCtx logs to the ERROR log of the Logger of ctx.
+// Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
+func ErrorCtx(ctx context.Context, args ...interface{}) {
+	ctxLogger(ctx).outputPrint(ERROR, CallDepth, args)
+}
+
+// ErrorfCtx logs to the ERROR log of the Logger of ctx.
+// Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
+func ErrorfCtx(ctx context.Context, format string, args ...interface{}) {
+	ctxLogger(ctx).outputPrintf(ERROR, CallDepth, format, args)
+}
+
+// ErrorwCtx logs msg and the key/value pairs in kv to the ERROR log of the Logger of ctx.
+func ErrorwCtx(ctx context.Context, msg string, kv ...interface{}) {
+	ctxLogger(ctx).output(ERROR, CallDepth, msg, makeFields(kv)...)
+}
+
+// VCtx acts as V for the Logger of ctx: it checks the level of that Logger
+// and the returned Verbose writes to it with the fields of ctx.
+//
+// Example:
+//
+//	cloudglog.VCtx(ctx, 2).Infof("loaded %d items", n)
+func VCtx(ctx context.Context, level int) Verbose {
+	v := FromContext(ctx).VDepth(1, level)
+	if v.enabled {
+		v.l = v.l.withContext(ctx)
+	}
+	return v
+}
+
+// InfoCtx is equivalent to Logger.Info with the fields of ctx, guarded by the value of v.
+func (v Verbose) InfoCtx(ctx context.Context, args ...interface{}) {
+	if v.enabled {
+		v.l.withContext(ctx).outputPrint(INFO, CallDepth, args)
+	}
+}
+
+// InfofCtx is equivalent to Logger.Infof with the fields of ctx, guarded by the value of v.
+func (v Verbose) InfofCtx(ctx context.Context, format string, args ...interface{}) {
+	if v.enabled {
+		v.l.withContext(ctx).outputPrintf(INFO, CallDepth, format, args)
+	}
+}
+
+// InfowCtx is equivalent to Logger.Infow with the fields of ctx, guarded by the value of v.
+func (v Verbose) InfowCtx(ctx context.Context, msg string, kv ...interface{}) {
+	if v.enabled {
+		v.l.withContext(ctx).output(INFO, CallDepth, msg, makeFields(kv)...)
+	}
+}
+
+// InfoCtx is equivalent to the global InfoCtx function, guarded by the value of v.
+// v is the level of the default Logger even though the record goes to the
+// Logger of ctx, use VCtx to check the level of that Logger.
+// See the documentation of V for usage.
+func (v Verbosity) InfoCtx(ctx context.Context, args ...interface{}) {
+	if v {
+		ctxLogger(ctx).outputPrint(INFO, CallDepth, args)
+	}
+}
+
+// InfofCtx is equivalent to the global InfofCtx function, guarded by the value of v.
+// v is the level of the default Logger, see InfoCtx.
+// See the documentation of V for usage.
+func (v Verbosity) InfofCtx(ctx context.Context, format string, args ...interface{}) {
+	if v {
+		ctxLogger(ctx).outputPrintf(INFO, CallDepth, format, args)
+	}
+}
+
+// InfowCtx is equivalent to the global InfowCtx function, guarded by the value of v.
+// v is the level of the default Logger, see InfoCtx.
+// See the documentation of V for usage.
+func (v Verbosity) InfowCtx(ctx context.Context, msg string, kv ...interface{}) {
+	if v {
+		ctxLogger(ctx).output(INFO, CallDepth, msg, makeFields(kv)...)
+	}
+}
@@ -0,0 +1,123 @@
+package cloudglog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Context(t *testing.T) {
+
+	assert.Equal(t, std, FromContext(context.Background()))
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+
+	ctx := l.NewContext(context.Background(), "req", "8f2b")
+	assert.NotEqual(t, l, FromContext(ctx))
+
+	// NewContext adds to the Logger of ctx
+	ctx = NewContext(ctx, "tenant", "acme")
+
+	tests := []struct {
+		log    func()
+		prefix string
+		suffix string
+	}{
+		{func() { InfoCtx(ctx, "started") }, "INFO: ", ": started req=8f2b tenant=acme\n"},
+		{func() { InfofCtx(ctx, "%d items", 12) }, "INFO: ", ": 12 items req=8f2b tenant=acme\n"},
+		{func() { InfowCtx(ctx, "done", "items", 12) }, "INFO: ", ": done req=8f2b tenant=acme items=12\n"},
+		{func() { WarningCtx(ctx, "slow") }, "WARNING: ", ": slow req=8f2b tenant=acme\n"},
+		{func() { WarningfCtx(ctx, "%s", "slow") }, "WARNING: ", ": slow req=8f2b tenant=acme\n"},
+		{func() { WarningwCtx(ctx, "slow", "ms", 900) }, "WARNING: ", ": slow req=8f2b tenant=acme ms=900\n"},
+		{func() { ErrorCtx(ctx, errors.New("boom")) }, "ERROR: ", ": boom req=8f2b tenant=acme\n"},
+		{func() { ErrorfCtx(ctx, "failed: %v", "boom") }, "ERROR: ", ": failed: boom req=8f2b tenant=acme\n"},
+		{func() { ErrorwCtx(ctx, "failed", "error", "boom") }, "ERROR: ", ": failed req=8f2b tenant=acme error=boom\n"},
+		{func() { V(0).InfoCtx(ctx, "verbose") }, "INFO: ", ": verbose req=8f2b tenant=acme\n"},
+		{func() { V(0).InfofCtx(ctx, "verbose %d", 0) }, "INFO: ", ": verbose 0 req=8f2b tenant=acme\n"},
+		{func() { V(0).InfowCtx(ctx, "verbose", "level", 0) }, "INFO: ", ": verbose req=8f2b tenant=acme level=0\n"},
+		{func() { VCtx(ctx, 2).Info("verbose") }, "INFO: ", ": verbose req=8f2b tenant=acme\n"},
+		{func() { VCtx(ctx, 2).Infow("verbose", "level", 2) }, "INFO: ", ": verbose req=8f2b tenant=acme level=2\n"},
+		{func() { l.V(2).InfoCtx(ctx, "verbose") }, "INFO: ", ": verbose\n"},
+		{func() { l.V(2).InfofCtx(ctx, "verbose %d", 2) }, "INFO: ", ": verbose 2\n"},
+		{func() { l.V(2).InfowCtx(ctx, "verbose", "level", 2) }, "INFO: ", ": verbose level=2\n"},
+	}
+
+	// VCtx checks the level of the Logger of ctx, not the default Logger's
+	l.SetLogLevel(2)
+	defer l.SetLogLevel(0)
+
+	for _, test := range tests {
+		b.Reset()
+		test.log()
+		assert.True(t, strings.HasPrefix(b.String(), test.prefix), "unexpected output %q", b.String())
+		assert.Contains(t, b.String(), "context_test.go:")
+		assert.True(t, strings.HasSuffix(b.String(), test.suffix), "unexpected output %q", b.String())
+	}
+
+	b.Reset()
+	Verbosity(false).InfofCtx(ctx, "hidden")
+	VCtx(ctx, 3).Info("hidden")
+	l.V(3).InfoCtx(ctx, "hidden")
+	TraceCtx(ctx, "hidden")
+	TracefCtx(ctx, "hidden")
+	assert.Equal(t, 0, b.Len())
+
+	// the vmodule of the Logger of ctx applies at the caller of VCtx
+	assert.NoError(t, l.SetVModule("context_test=3"))
+	assert.True(t, VCtx(ctx, 3).Enabled())
+	assert.NoError(t, l.SetVModule(""))
+
+	l.EnableTrace(true)
+	TracefCtx(ctx, "%s", "shown")
+	assert.True(t, strings.HasPrefix(b.String(), "TRACE: "), "unexpected output %q", b.String())
+	assert.True(t, strings.HasSuffix(b.String(), ": shown req=8f2b tenant=acme\n"), "unexpected output %q", b.String())
+}
+
+type ctxTestKey struct{}
+
+func Test_RegisterContextFields(t *testing.T) {
+
+	unregister := RegisterContextFields(func(ctx context.Context) []Field {
+		if v, ok := ctx.Value(ctxTestKey{}).(string); ok {
+			return []Field{{Key: "span", Value: v}}
+		}
+		return nil
+	})
+	t.Cleanup(unregister)
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+	ctx := l.NewContext(context.WithValue(context.Background(), ctxTestKey{}, "s1"), "req", "8f2b")
+
+	InfoCtx(ctx, "with span")
+	assert.True(t, strings.HasSuffix(b.String(), ": with span req=8f2b span=s1\n"), "unexpected output %q", b.String())
+
+	b.Reset()
+	V(0).InfowCtx(ctx, "verbose", "n", 1)
+	assert.True(t, strings.HasSuffix(b.String(), ": verbose req=8f2b span=s1 n=1\n"), "unexpected output %q", b.String())
+
+	b.Reset()
+	VCtx(ctx, 0).Info("verbose")
+	assert.True(t, strings.HasSuffix(b.String(), ": verbose req=8f2b span=s1\n"), "unexpected output %q", b.String())
+
+	// a Verbose of another Logger only takes the fields of the functions
+	b.Reset()
+	l.V(0).InfoCtx(ctx, "verbose")
+	assert.True(t, strings.HasSuffix(b.String(), ": verbose span=s1\n"), "unexpected output %q", b.String())
+
+	// the Logger of ctx is left alone
+	b.Reset()
+	FromContext(ctx).Info("plain")
+	assert.True(t, strings.HasSuffix(b.String(), ": plain req=8f2b\n"), "unexpected output %q", b.String())
+
+	// unregistered functions add no more fields
+	unregister()
+	b.Reset()
+	InfoCtx(ctx, "without span")
+	assert.True(t, strings.HasSuffix(b.String(), ": without span req=8f2b\n"), "unexpected output %q", b.String())
+}
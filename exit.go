@@ -0,0 +1,102 @@
+package cloudglog
+
+import (
+	"bytes"
+	"os"
+	"sync"
+)
+
+var (
+	exitMu sync.Mutex
+
+	// exitFunc terminates the process after Fatal and Exit
+	exitFunc = os.Exit
+)
+
+// SetExitFunc replaces os.Exit as the function Fatal and Exit call after
+// logging and flushing, nil restores os.Exit. If f returns, so does the
+// Fatal or Exit call and the caller continues, which is meant for tests.
+func SetExitFunc(f func(code int)) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+
+	if f == nil {
+		f = os.Exit
+	}
+	exitFunc = f
+}
+
+// callExit calls the exit function with code
+func callExit(code int) {
+	exitMu.Lock()
+	f := exitFunc
+	exitMu.Unlock()
+
+	f(code)
+}
+
+// exitCode is the panic CaptureExit stops a Fatal or Exit call with
+type exitCode int
+
+// CaptureExit runs f with all outputs of l going to a buffer and with an
+// exit function that stops the Fatal or Exit call f makes. It returns the
+// output and the exit code, or -1 if f returned without exiting. Fatal and
+// Exit must be called in the goroutine of f, and as the exit function is
+// shared by all Loggers, tests that use CaptureExit must not run in parallel.
+//
+// Example:
+//
+//	out, code := l.CaptureExit(func() { run(l) })
+//	assert.Equal(t, 1, code)
+//	assert.Contains(t, out, "cannot open config")
+func (l *Logger) CaptureExit(f func()) (output string, code int) {
+
+	var b bytes.Buffer
+
+	l.mu.Lock()
+	out, stderr := l.out, l.stderr
+	l.setupLogger(&b, &b, &b, &b, &b)
+	l.stderr = &b
+	l.mu.Unlock()
+
+	exitMu.Lock()
+	previous := exitFunc
+	exitFunc = func(code int) { panic(exitCode(code)) }
+	exitMu.Unlock()
+
+	defer func() {
+		exitMu.Lock()
+		exitFunc = previous
+		exitMu.Unlock()
+
+		l.mu.Lock()
+		l.out, l.stderr = out, stderr
+		l.mu.Unlock()
+	}()
+
+	code = -1
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				c, ok := r.(exitCode)
+				if !ok {
+					panic(r)
+				}
+				code = int(c)
+			}
+		}()
+		f()
+	}()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return b.String(), code
+}
+
+// CaptureExit runs f with the outputs of the default Logger going to a
+// buffer and returns the output and the exit code of the Fatal or Exit call
+// f makes, see Logger.CaptureExit.
+func CaptureExit(f func()) (output string, code int) {
+	return std.CaptureExit(f)
+}
@@ -0,0 +1,67 @@
+package cloudglog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CaptureExit(t *testing.T) {
+
+	l := New(nil, DefaultFormat, NoColor, 0)
+
+	reached := false
+	out, code := l.CaptureExit(func() {
+		l.Info("starting")
+		l.Fatalf("cannot open %s", "config")
+		reached = true
+	})
+	assert.Equal(t, 1, code)
+	assert.False(t, reached, "Fatal must not return")
+	assert.True(t, strings.HasPrefix(out, "INFO: "), "unexpected output %q", out)
+	assert.Contains(t, out, ": cannot open config\ngoroutine ")
+
+	out, code = l.CaptureExit(func() { l.Exit("bye") })
+	assert.Equal(t, 1, code)
+	assert.True(t, strings.HasSuffix(out, ": bye\n"), "unexpected output %q", out)
+
+	out, code = l.CaptureExit(func() { l.Warning("no exit") })
+	assert.Equal(t, -1, code)
+	assert.Contains(t, out, "no exit")
+
+	// the outputs are restored
+	assert.Nil(t, l.out[INFO])
+}
+
+func Test_CaptureExitPackage(t *testing.T) {
+
+	out, code := CaptureExit(func() { Exitf("code %d", 1) })
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "code 1")
+
+	// other panics are passed on
+	assert.PanicsWithValue(t, "boom", func() {
+		CaptureExit(func() { panic("boom") })
+	})
+}
+
+func Test_VerbosityFatalExits(t *testing.T) {
+
+	// the package V and the Logger V both exit on Fatal
+	for _, fatal := range []func(){
+		func() { V(0).Fatal("fatal") },
+		func() { V(0).FatalDepth(0, "fatal") },
+		func() { V(0).Fatalln("fatal") },
+		func() { V(0).Fatalf("%s", "fatal") },
+		func() { std.V(0).Fatal("fatal") },
+	} {
+		out, code := CaptureExit(fatal)
+		assert.Equal(t, 1, code)
+		assert.Contains(t, out, "fatal\ngoroutine ")
+	}
+
+	// a disabled V does not exit
+	_, code := CaptureExit(func() { V(100).Fatal("hidden") })
+	assert.Equal(t, -1, code)
+}
@@ -0,0 +1,129 @@
+package cloudglog
+
+import (
+	"fmt"
+)
+
+// Field is a key/value pair that is carried with a log record, text
+// formats render it as key=value after the message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// makeFields converts alternating keys and values into Fields, Field
+// values in kv are taken as they are. A key without a value gets "(MISSING)".
+func makeFields(kv []interface{}) []Field {
+
+	fields := make([]Field, 0, len(kv)/2)
+
+	for i := 0; i < len(kv); i++ {
+
+		if f, ok := kv[i].(Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+
+		if i+1 == len(kv) {
+			fields = append(fields, Field{Key: key, Value: "(MISSING)"})
+			break
+		}
+
+		fields = append(fields, Field{Key: key, Value: kv[i+1]})
+		i++
+	}
+
+	return fields
+}
+
+// With returns a Logger that adds the key/value pairs in kv to every
+// record it logs. It shares outputs and settings with l.
+//
+// Example:
+//
+//	l.With("user", id, "req", rid).Info("request done")
+func (l *Logger) With(kv ...interface{}) *Logger {
+	return l.withFields(makeFields(kv))
+}
+
+// withFields returns a Logger that adds fields to every record it logs
+func (l *Logger) withFields(fields []Field) *Logger {
+	fs := l.fields[:len(l.fields):len(l.fields)]
+	return &Logger{settings: l.settings, fields: append(fs, fields...)}
+}
+
+// Infow logs msg and the key/value pairs in kv to the INFO log.
+func (l *Logger) Infow(msg string, kv ...interface{}) {
+	l.output(INFO, CallDepth, msg, makeFields(kv)...)
+}
+
+// Warningw logs msg and the key/value pairs in kv to the WARNING log.
+func (l *Logger) Warningw(msg string, kv ...interface{}) {
+	l.output(WARNING, CallDepth, msg, makeFields(kv)...)
+}
+
+// Errorw logs msg and the key/value pairs in kv to the ERROR log.
+func (l *Logger) Errorw(msg string, kv ...interface{}) {
+	l.output(ERROR, CallDepth, msg, makeFields(kv)...)
+}
+
+// Fatalw logs msg and the key/value pairs in kv to the FATAL log including
+// the stacks of all goroutines, then flushes, runs the exit hooks and calls
+// the exit function, os.Exit(1) by default, see SetExitFunc.
+func (l *Logger) Fatalw(msg string, kv ...interface{}) {
+	l.fatalOutput(CallDepth, msg, makeFields(kv)...)
+	l.exit()
+}
+
+// Infow is equivalent to Logger.Infow, guarded by the value of v.
+func (v Verbose) Infow(msg string, kv ...interface{}) {
+	if v.enabled {
+		v.l.output(INFO, CallDepth, msg, makeFields(kv)...)
+	}
+}
+
+// With returns a Logger based on the default Logger that adds the
+// key/value pairs in kv to every record it logs.
+//
+// Example:
+//
+//	cloudglog.With("user", id, "req", rid).Info("request done")
+func With(kv ...interface{}) *Logger {
+	return std.With(kv...)
+}
+
+// Infow logs msg and the key/value pairs in kv to the INFO log.
+func Infow(msg string, kv ...interface{}) {
+	std.output(INFO, CallDepth, msg, makeFields(kv)...)
+}
+
+// Warningw logs msg and the key/value pairs in kv to the WARNING log.
+func Warningw(msg string, kv ...interface{}) {
+	std.output(WARNING, CallDepth, msg, makeFields(kv)...)
+}
+
+// Errorw logs msg and the key/value pairs in kv to the ERROR log.
+func Errorw(msg string, kv ...interface{}) {
+	std.output(ERROR, CallDepth, msg, makeFields(kv)...)
+}
+
+// Fatalw logs msg and the key/value pairs in kv to the FATAL log including
+// the stacks of all goroutines, then flushes, runs the exit hooks and calls
+// the exit function, os.Exit(1) by default, see SetExitFunc.
+func Fatalw(msg string, kv ...interface{}) {
+	std.fatalOutput(CallDepth, msg, makeFields(kv)...)
+	std.exit()
+}
+
+// Infow is equivalent to the global Infow function, guarded by the value of v.
+// See the documentation of V for usage.
+func (v Verbosity) Infow(msg string, kv ...interface{}) {
+	if v {
+		std.output(INFO, CallDepth, msg, makeFields(kv)...)
+	}
+}
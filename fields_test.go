@@ -0,0 +1,34 @@
+package cloudglog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MakeFields(t *testing.T) {
+
+	fields := makeFields([]interface{}{"user", 42, Field{Key: "req", Value: "abc"}, 7, true, "odd"})
+
+	assert.Equal(t, []Field{
+		{Key: "user", Value: 42},
+		{Key: "req", Value: "abc"},
+		{Key: "7", Value: true},
+		{Key: "odd", Value: "(MISSING)"},
+	}, fields)
+}
+
+func Test_With(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+
+	l.With("user", 42).With("req", "a b").Infow("done", "took", "1s")
+	assert.True(t, strings.HasSuffix(b.String(), `: done user=42 req="a b" took=1s`+"\n"), "unexpected output %q", b.String())
+
+	b.Reset()
+	l.Infoln("plain")
+	assert.True(t, strings.HasSuffix(b.String(), ": plain\n"), "parent got child fields %q", b.String())
+}
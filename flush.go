@@ -0,0 +1,141 @@
+package cloudglog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	exitHooksMu sync.Mutex
+	exitHooks   []func() error
+
+	// exitTimeout bounds flushing before Fatal and Exit, accessed atomically
+	exitTimeout = int64(5 * time.Second)
+)
+
+// RegisterExitHook registers f to run before Fatal and Exit terminate the
+// process, use it to flush or close what the logs pass through on their
+// way out. Hooks run after the outputs are flushed, the last registered
+// first, errors are written to stderr.
+func RegisterExitHook(f func() error) {
+	exitHooksMu.Lock()
+	defer exitHooksMu.Unlock()
+	exitHooks = append(exitHooks, f)
+}
+
+// SetExitTimeout bounds the time flushing and the exit hooks may take
+// before Fatal and Exit terminate the process, the default is 5 seconds.
+func SetExitTimeout(d time.Duration) {
+	atomic.StoreInt64(&exitTimeout, int64(d))
+}
+
+// flusher is implemented by buffering writers like bufio.Writer
+type flusher interface {
+	Flush() error
+}
+
+// syncer is implemented by files like os.File
+type syncer interface {
+	Sync() error
+}
+
+// Flush flushes the outputs of l that have a Flush method, like
+// bufio.Writer, and syncs those that have a Sync method, like os.File.
+// Errors syncing stdout and stderr, which cannot be synced when they
+// are a terminal or a pipe, are ignored.
+func (l *Logger) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var firstErr error
+	outputs := append(l.out[:len(l.out):len(l.out)], l.stderr)
+
+	for i, w := range outputs {
+
+		// outputs shared between logTypes are flushed once
+		flushed := false
+		for _, u := range outputs[:i] {
+			if sameWriter(u, w) {
+				flushed = true
+				break
+			}
+		}
+
+		if !flushed {
+			if err := flushWriter(w); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// Flush flushes the outputs of the default Logger, see Logger.Flush.
+func Flush() error {
+	return std.Flush()
+}
+
+// flushWriter flushes or syncs w
+func flushWriter(w io.Writer) error {
+
+	switch w := w.(type) {
+	case flusher:
+		return w.Flush()
+	case syncer:
+		err := w.Sync()
+		if w == os.Stdout || w == os.Stderr {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// exit flushes l and the default Logger and runs the exit hooks within
+// the exit timeout, then terminates the process, see SetExitFunc.
+func (l *Logger) exit() {
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.flushAll()
+	}()
+
+	timeout := time.Duration(atomic.LoadInt64(&exitTimeout))
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		fmt.Fprintf(os.Stderr, "cloudglog: flushing before exit timed out after %v\n", timeout)
+	}
+
+	callExit(1)
+}
+
+// flushAll flushes l and the default Logger and runs the exit hooks
+func (l *Logger) flushAll() {
+
+	if err := l.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "cloudglog: flushing before exit: %v\n", err)
+	}
+	if l.settings != std.settings {
+		if err := std.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "cloudglog: flushing before exit: %v\n", err)
+		}
+	}
+
+	exitHooksMu.Lock()
+	hooks := append([]func() error(nil), exitHooks...)
+	exitHooksMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](); err != nil {
+			fmt.Fprintf(os.Stderr, "cloudglog: exit hook: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cloudglog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Flush(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	l := New(w, DefaultFormat, NoColor, 0)
+
+	l.Info("buffered")
+	assert.Equal(t, 0, b.Len())
+
+	assert.NoError(t, l.Flush())
+	assert.True(t, strings.HasSuffix(b.String(), ": buffered\n"), "unexpected output %q", b.String())
+}
+
+func Test_ExitHooks(t *testing.T) {
+
+	defer func(hooks []func() error) { exitHooks = hooks }(exitHooks)
+	exitHooks = nil
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	l := New(w, DefaultFormat, NoColor, 0)
+	l.Info("buffered")
+
+	var order []string
+	var flushed bool
+	RegisterExitHook(func() error {
+		order = append(order, "first")
+		return nil
+	})
+	RegisterExitHook(func() error {
+		order = append(order, "second")
+		flushed = strings.Contains(b.String(), "buffered")
+		return errors.New("ignored")
+	})
+
+	l.flushAll()
+	assert.Equal(t, []string{"second", "first"}, order)
+	assert.True(t, flushed, "outputs are flushed before the hooks run")
+}
+
+// Test_FatalFlushes runs Fatal in a child process and checks that the
+// buffered output made it out before the process terminated.
+func Test_FatalFlushes(t *testing.T) {
+
+	if os.Getenv("CLOUDGLOG_FATAL_CHILD") == "1" {
+		w := bufio.NewWriter(os.Stdout)
+		LogFile(w)
+		RegisterExitHook(func() error {
+			os.Stdout.WriteString("hook ran\n")
+			return nil
+		})
+		SetExitTimeout(time.Second)
+		Fatal("buffered fatal")
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^Test_FatalFlushes$")
+	cmd.Env = append(os.Environ(), "CLOUDGLOG_FATAL_CHILD=1")
+	out, err := cmd.Output()
+
+	var exitErr *exec.ExitError
+	assert.True(t, errors.As(err, &exitErr), "unexpected error %v", err)
+	assert.Equal(t, 1, exitErr.ExitCode())
+	assert.Contains(t, string(out), ": buffered fatal\ngoroutine ")
+	assert.True(t, strings.HasSuffix(string(out), "hook ran\n"), "unexpected output %q", out)
+}
@@ -0,0 +1,165 @@
+package cloudglog
+
+import (
+	"strings"
+	"sync"
+)
+
+// Formatter renders records, Format appends r including a trailing newline
+// to buf and returns the extended buffer. Install a Formatter with
+// RegisterFormat to use it with FormatStyle.
+type Formatter interface {
+	Format(buf []byte, r *Record) []byte
+}
+
+type namedFormatter struct {
+	name string
+	Formatter
+}
+
+var (
+	formattersMu sync.RWMutex
+
+	// formatters indexed by formatStyle
+	formatters = []namedFormatter{
+		DefaultFormat:      {"default", textFormatter{}},
+		ModernFormat:       {"modern", textFormatter{modern: true}},
+		JSONFormat:         {"json", jsonFormatter{}},
+		CloudLoggingFormat: {"cloud", cloudFormatter{}},
+	}
+)
+
+// RegisterFormat installs f under name and returns the formatStyle to select
+// it with FormatStyle, registering an existing name replaces its Formatter.
+// Records of a formatStyle without Formatter, like a nil f, are written in
+// DefaultFormat.
+//
+// Example:
+//
+//	csv := cloudglog.RegisterFormat("csv", myCSVFormatter{})
+//	cloudglog.FormatStyle(csv)
+func RegisterFormat(name string, f Formatter) formatStyle {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+
+	for i := range formatters {
+		if formatters[i].name == name {
+			formatters[i].Formatter = f
+			return formatStyle(i)
+		}
+	}
+
+	formatters = append(formatters, namedFormatter{name, f})
+	return formatStyle(len(formatters) - 1)
+}
+
+// String returns the name the format is registered with
+func (f formatStyle) String() string {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+
+	if f < 0 || int(f) >= len(formatters) {
+		return "unknown"
+	}
+	return formatters[f].name
+}
+
+// formatByName returns the formatStyle registered as name
+func formatByName(name string) (formatStyle, bool) {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+
+	for i := range formatters {
+		if formatters[i].name == name {
+			return formatStyle(i), true
+		}
+	}
+	return 0, false
+}
+
+// formatter returns the Formatter of f, the one of DefaultFormat if no
+// Formatter is registered for f, so records are never dropped
+func formatter(f formatStyle) Formatter {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+
+	if f >= 0 && int(f) < len(formatters) && formatters[f].Formatter != nil {
+		return formatters[f].Formatter
+	}
+	if formatters[DefaultFormat].Formatter != nil {
+		return formatters[DefaultFormat].Formatter
+	}
+	return textFormatter{}
+}
+
+// formatRecord appends r rendered with the format and settings of l to buf,
+// l.mu must be held.
+func (l *Logger) formatRecord(buf []byte, r *Record) []byte {
+
+	f := formatter(l.format)
+
+	r.Color = l.color
+	r.FileLength = l.fileLength
+
+	return f.Format(buf, r)
+}
+
+// textFormatter implements DefaultFormat and ModernFormat
+type textFormatter struct {
+	modern bool
+}
+
+func (t textFormatter) Format(buf []byte, r *Record) []byte {
+	return r.appendText(buf, t.modern)
+}
+
+// jsonFormatter implements JSONFormat, JSON is never colored
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(buf []byte, r *Record) []byte {
+	return r.appendJSON(buf)
+}
+
+// cloudFormatter implements CloudLoggingFormat
+type cloudFormatter struct{}
+
+func (cloudFormatter) Format(buf []byte, r *Record) []byte {
+	return r.appendCloud(buf)
+}
+
+// String returns the severity name of t
+func (t logType) String() string {
+	if t < 0 || int(t) >= numLogTypes {
+		return "UNKNOWN"
+	}
+	return severities[t]
+}
+
+// colorStyleNames are the names of the colorStyles as in the source
+var colorStyleNames = []string{
+	NoColor:                  "NoColor",
+	PrefixColor:              "PrefixColor",
+	PrefixBoldColor:          "PrefixBoldColor",
+	FullColor:                "FullColor",
+	FullBoldColor:            "FullBoldColor",
+	FullColorWithBoldMessage: "FullColorWithBoldMessage",
+	FullColorWithBoldPrefix:  "FullColorWithBoldPrefix",
+}
+
+// String returns the name of the color style
+func (c colorStyle) String() string {
+	if c < 0 || int(c) >= len(colorStyleNames) {
+		return "unknown"
+	}
+	return colorStyleNames[c]
+}
+
+// colorStyleByName returns the colorStyle called name, case is ignored
+func colorStyleByName(name string) (colorStyle, bool) {
+	for i, n := range colorStyleNames {
+		if strings.EqualFold(n, name) {
+			return colorStyle(i), true
+		}
+	}
+	return 0, false
+}
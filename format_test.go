@@ -0,0 +1,53 @@
+package cloudglog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type levelFormatter struct {
+	prefix string
+}
+
+func (f levelFormatter) Format(buf []byte, r *Record) []byte {
+	buf = append(buf, f.prefix...)
+	buf = append(buf, r.Level.String()...)
+	buf = append(buf, ' ')
+	buf = append(buf, r.Message...)
+	return append(buf, '\n')
+}
+
+func Test_RegisterFormat(t *testing.T) {
+
+	assert.Equal(t, "default", DefaultFormat.String())
+	assert.Equal(t, "cloud", CloudLoggingFormat.String())
+	assert.Equal(t, "unknown", formatStyle(-1).String())
+
+	style := RegisterFormat("level", levelFormatter{prefix: "1 "})
+	assert.Equal(t, "level", style.String())
+
+	var b bytes.Buffer
+	l := New(&b, style, NoColor, 0)
+	l.Warning("msg")
+	assert.Equal(t, "1 WARNING msg\n", b.String())
+
+	// registering the same name again replaces the formatter
+	assert.Equal(t, style, RegisterFormat("level", levelFormatter{prefix: "2 "}))
+
+	b.Reset()
+	l.Error("msg")
+	assert.Equal(t, "2 ERROR msg\n", b.String())
+}
+
+func Test_UnknownFormat(t *testing.T) {
+
+	// styles without Formatter are written in DefaultFormat
+	for _, style := range []formatStyle{formatStyle(99), formatStyle(-1), RegisterFormat("nil", nil)} {
+		var b bytes.Buffer
+		l := New(&b, style, NoColor, 0)
+		l.Info("msg")
+		assert.Regexp(t, `^INFO: \d{4}/\d\d/\d\d \d\d:\d\d:\d\d .*format_test.go:\d+: msg\n$`, b.String())
+	}
+}
@@ -0,0 +1,184 @@
+package cloudglog
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// severities are the names of the logTypes in machine formats
+var severities = []string{
+	TRACE:   "TRACE",
+	INFO:    "INFO",
+	WARNING: "WARNING",
+	ERROR:   "ERROR",
+	FATAL:   "FATAL",
+}
+
+// jsonKeys are written by appendJSON, fields with the same key are prefixed with "fields."
+var jsonKeys = map[string]bool{
+	"timestamp": true,
+	"severity":  true,
+	"file":      true,
+	"line":      true,
+	"package":   true,
+	"message":   true,
+	"stack":     true,
+}
+
+// appendJSON appends the record as one JSON object followed by a newline
+func (r *Record) appendJSON(buf []byte) []byte {
+
+	file, line, _ := r.Caller()
+	dir, file := path.Split(file)
+
+	buf = append(buf, `{"timestamp":"`...)
+	buf = r.Time.AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, '"')
+	buf = append(buf, `,"severity":`...)
+	buf = appendJSONString(buf, severities[r.Level])
+	buf = append(buf, `,"file":`...)
+	buf = appendJSONString(buf, file)
+	buf = append(buf, `,"line":`...)
+	buf = strconv.AppendInt(buf, int64(line), 10)
+	buf = append(buf, `,"package":`...)
+	buf = appendJSONString(buf, path.Base(dir))
+	buf = append(buf, `,"message":`...)
+	buf = appendJSONString(buf, strings.TrimSuffix(r.Message, "\n"))
+	if len(r.Stack) > 0 {
+		buf = append(buf, `,"stack":`...)
+		buf = appendJSONString(buf, string(r.Stack))
+	}
+	buf = appendJSONFields(buf, r.Fields, jsonKeys)
+	buf = append(buf, "}\n"...)
+
+	return buf
+}
+
+// appendJSONFields appends the fields as JSON members, keys found in reserved
+// and keys that already start with "fields." are prefixed with "fields.", so
+// renamed keys cannot collide with other fields. A field replaces earlier
+// fields with the same key, it is written at the place of the last of them.
+func appendJSONFields(buf []byte, fields []Field, reserved map[string]bool) []byte {
+
+	for i, f := range fields {
+		key := jsonKey(f.Key, reserved)
+		if hasJSONKey(fields[i+1:], key, reserved) {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = appendJSONString(buf, key)
+		buf = append(buf, ':')
+		buf = appendJSONValue(buf, f.Value)
+	}
+
+	return buf
+}
+
+// jsonKey returns the member name of a field with key
+func jsonKey(key string, reserved map[string]bool) string {
+	if reserved[key] || strings.HasPrefix(key, "fields.") {
+		return "fields." + key
+	}
+	return key
+}
+
+// hasJSONKey reports whether one of fields is written as member key
+func hasJSONKey(fields []Field, key string, reserved map[string]bool) bool {
+	for _, f := range fields {
+		if jsonKey(f.Key, reserved) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// appendJSONValue appends v encoded as JSON, errors and values that cannot
+// be encoded are written as strings.
+func appendJSONValue(buf []byte, v interface{}) []byte {
+
+	switch v := v.(type) {
+	case string:
+		return appendJSONString(buf, v)
+	case error:
+		return appendJSONString(buf, v.Error())
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(buf, fmt.Sprint(v))
+	}
+
+	return append(buf, b...)
+}
+
+const hex = "0123456789abcdef"
+
+// appendJSONString appends s as a quoted JSON string, control characters
+// are escaped and invalid UTF-8 is replaced with U+FFFD.
+func appendJSONString(buf []byte, s string) []byte {
+
+	buf = append(buf, '"')
+
+	start := 0
+	for i := 0; i < len(s); {
+
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != 0x7f {
+				i++
+				continue
+			}
+
+			buf = append(buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+
+		// U+2028 and U+2029 break JavaScript parsers
+		if r == '\u2028' || r == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+
+		i += size
+	}
+
+	buf = append(buf, s[start:]...)
+
+	return append(buf, '"')
+}
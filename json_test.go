@@ -0,0 +1,66 @@
+package cloudglog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_JSONFormat(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, JSONFormat, FullColor, 0)
+
+	l.With("user", 42, "err", errors.New("boom"), "message", "dup").Warningln("line one\nline \"two\"\t\x01")
+
+	assert.Equal(t, byte('\n'), b.Bytes()[b.Len()-1])
+	assert.Equal(t, 1, bytes.Count(b.Bytes(), []byte("\n")), "one object per line")
+
+	var obj map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &obj), "invalid json %q", b.String())
+
+	assert.Equal(t, "WARNING", obj["severity"])
+	assert.Equal(t, "json_test.go", obj["file"])
+	assert.Equal(t, "line one\nline \"two\"\t\x01", obj["message"])
+	assert.Equal(t, float64(42), obj["user"])
+	assert.Equal(t, "boom", obj["err"])
+	assert.Equal(t, "dup", obj["fields.message"])
+	assert.Contains(t, obj, "timestamp")
+	assert.Contains(t, obj, "line")
+	assert.Contains(t, obj, "package")
+}
+
+func Test_JSONDuplicateKeys(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, JSONFormat, NoColor, 0)
+
+	l.With("a", 1, "message", "first").Infow("m", "b", true, "a", 2)
+	assert.Contains(t, b.String(), `,"fields.message":"first","b":true,"a":2}`)
+	assert.Equal(t, 1, bytes.Count(b.Bytes(), []byte(`"a":`)), "unexpected output %q", b.String())
+
+	// a renamed reserved key and a field named like it are both kept
+	b.Reset()
+	l.Infow("m", "message", "first", "fields.message", "second")
+	var obj map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &obj), "invalid json %q", b.String())
+	assert.Equal(t, "m", obj["message"])
+	assert.Equal(t, "first", obj["fields.message"])
+	assert.Equal(t, "second", obj["fields.fields.message"])
+}
+
+func Test_AppendJSONString(t *testing.T) {
+
+	for _, s := range []string{"", "plain", "a\\b", "\x00\x1f\x7f", "\u2028", "ünï", "\xff"} {
+
+		var out string
+		assert.NoError(t, json.Unmarshal(appendJSONString(nil, s), &out), "invalid json for %q", s)
+
+		if s != "\xff" {
+			assert.Equal(t, s, out)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package cloudglog
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+)
+
+// DefaultMaxLineLength is the longest line a LineWriter logs as one record
+const DefaultMaxLineLength = 64 << 10
+
+// LineWriter is an io.WriteCloser that logs every complete line written to
+// it as a record of one log, for os/exec, http.Server.ErrorLog and other
+// code that wants an io.Writer. Partial lines are kept until their newline
+// arrives or the writer is closed, a trailing carriage return is dropped.
+// Lines longer than the maximum line length are logged in pieces.
+//
+// The records are attributed to the caller of Writer unless SetCaller
+// names another one.
+//
+// Example:
+//
+//	cmd := exec.Command("make")
+//	cmd.Stdout = cloudglog.Writer(cloudglog.INFO)
+//	cmd.Stderr = cloudglog.Writer(cloudglog.WARNING)
+type LineWriter struct {
+	l       *Logger
+	logType logType
+
+	mu  sync.Mutex
+	buf []byte // the partial line
+	max int
+	pc  uintptr // of the caller of Writer
+
+	// caller of SetCaller
+	callerSet bool
+	file      string
+	line      int
+	function  string
+}
+
+// Writer returns a LineWriter that logs lines to the t log of l.
+func (l *Logger) Writer(t logType) *LineWriter {
+	return l.newLineWriter(t, 1)
+}
+
+// WriterDepth acts as Writer but uses depth to determine which call frame
+// the records are attributed to. WriterDepth(0, t) is the same as Writer(t).
+func (l *Logger) WriterDepth(depth int, t logType) *LineWriter {
+	return l.newLineWriter(t, depth+1)
+}
+
+// newLineWriter returns a LineWriter attributed to the caller depth
+// frames above the caller of newLineWriter
+func (l *Logger) newLineWriter(t logType, depth int) *LineWriter {
+
+	var pc [1]uintptr
+	runtime.Callers(depth+2, pc[:])
+
+	return &LineWriter{l: l, logType: t, max: DefaultMaxLineLength, pc: pc[0]}
+}
+
+// SetMaxLineLength sets the longest line logged as one record, n below 1
+// is DefaultMaxLineLength.
+func (w *LineWriter) SetMaxLineLength(n int) {
+	if n < 1 {
+		n = DefaultMaxLineLength
+	}
+	w.mu.Lock()
+	w.max = n
+	w.mu.Unlock()
+}
+
+// SetCaller attributes the records to file, line and function instead of
+// the caller of Writer, for example the command an os/exec output belongs to.
+func (w *LineWriter) SetCaller(file string, line int, function string) {
+	w.mu.Lock()
+	w.callerSet = true
+	w.file, w.line, w.function = file, line, function
+	w.mu.Unlock()
+}
+
+// Write logs the complete lines in p and keeps the rest. It never fails.
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			break
+		}
+		w.buf = append(w.buf, p[:i]...)
+		w.flushLine()
+		p = p[i+1:]
+	}
+
+	// cap a partial line that keeps growing
+	for len(w.buf) > w.max {
+		w.logLine(w.buf[:w.max])
+		w.buf = append(w.buf[:0], w.buf[w.max:]...)
+	}
+
+	return n, nil
+}
+
+// Close logs a remaining partial line, the LineWriter stays usable.
+func (w *LineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.flushLine()
+	}
+
+	return nil
+}
+
+// flushLine logs w.buf as complete line and empties it, w.mu must be held.
+func (w *LineWriter) flushLine() {
+
+	line := bytes.TrimSuffix(w.buf, []byte{'\r'})
+	for len(line) > w.max {
+		w.logLine(line[:w.max])
+		line = line[w.max:]
+	}
+	w.logLine(line)
+
+	w.buf = w.buf[:0]
+}
+
+// logLine logs one line, w.mu must be held.
+func (w *LineWriter) logLine(line []byte) {
+
+	if w.logType == TRACE && !w.l.TraceEnabled() {
+		return
+	}
+
+	// the record is written before logLine returns, line is not kept
+	r := Record{Level: w.logType, Message: bytesToString(line), pc: w.pc}
+	if w.callerSet {
+		r.SetCaller(w.file, w.line, w.function)
+	}
+
+	w.l.LogRecord(r)
+}
+
+// Writer returns a LineWriter that logs lines to the t log of the default
+// Logger, see LineWriter.
+func Writer(t logType) *LineWriter {
+	return std.newLineWriter(t, 1)
+}
+
+// WriterDepth acts as Writer but uses depth to determine which call frame
+// the records are attributed to.
+func WriterDepth(depth int, t logType) *LineWriter {
+	return std.newLineWriter(t, depth+1)
+}
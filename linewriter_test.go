@@ -0,0 +1,92 @@
+package cloudglog
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LineWriter(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+	w := l.Writer(WARNING)
+	_, _, line, _ := runtime.Caller(0)
+
+	n, err := w.Write([]byte("first\nsec"))
+	assert.NoError(t, err)
+	assert.Equal(t, 9, n)
+	assert.Equal(t, 1, strings.Count(b.String(), "\n"))
+
+	w.Write([]byte("ond\r\nthi"))
+	w.Write([]byte("rd"))
+	assert.Equal(t, 2, strings.Count(b.String(), "\n"))
+
+	assert.NoError(t, w.Close())
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	assert.Len(t, lines, 3)
+	for i, msg := range []string{"first", "second", "third"} {
+		assert.True(t, strings.HasPrefix(lines[i], "WARNING: "), "unexpected line %q", lines[i])
+		assert.True(t, strings.HasSuffix(lines[i], fmt.Sprintf("/linewriter_test.go:%d: %s", line-1, msg)), "unexpected line %q", lines[i])
+	}
+}
+
+func Test_LineWriterMaxLineLength(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+	w := l.Writer(INFO)
+	w.SetMaxLineLength(4)
+	w.SetCaller("make", 0, "")
+
+	w.Write([]byte("abcdefghij\n"))
+	w.Write([]byte("klmnop"))
+	w.Close()
+
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		assert.Contains(t, line, " make:0: ")
+		msgs = append(msgs, line[strings.Index(line, " make:0: ")+9:])
+	}
+	assert.Equal(t, []string{"abcd", "efgh", "ij", "klmn", "op"}, msgs)
+}
+
+func Test_LineWriterTrace(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+	w := l.Writer(TRACE)
+
+	w.Write([]byte("hidden\n"))
+	assert.Empty(t, b.String())
+
+	l.EnableTrace(true)
+	w.Write([]byte("shown\n"))
+	assert.True(t, strings.HasPrefix(b.String(), "TRACE: "), "unexpected output %q", b.String())
+}
+
+func Test_LineWriterExec(t *testing.T) {
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+
+	cmd := exec.Command(sh, "-c", "echo out; echo err >&2")
+	cmd.Stdout = l.Writer(INFO)
+	cmd.Stderr = l.Writer(ERROR)
+	assert.NoError(t, cmd.Run())
+
+	assert.Contains(t, b.String(), ": out\n")
+	assert.Contains(t, b.String(), ": err\n")
+	assert.Contains(t, b.String(), "ERROR: ")
+}
@@ -0,0 +1,214 @@
+package cloudglog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logDirSeverities are the severities glog writes files for, TRACE goes to the INFO file
+var logDirSeverities = []logType{INFO, WARNING, ERROR, FATAL}
+
+// LogDir writes the logs to files in dir using the glog layout
+//
+//	program.host.user.log.SEVERITY.yyyymmdd-hhmmss.pid
+//
+// with one file per severity that also receives all higher severities, and
+// a program.SEVERITY symlink to the current file. Files are created on
+// their first write, dir is created if it does not exist.
+func (l *Logger) LogDir(dir string) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := make(map[logType]*logDirFile, len(logDirSeverities))
+	for _, s := range logDirSeverities {
+		files[s] = &logDirFile{dir: dir, severity: s}
+	}
+
+	// each severity writes to its own and all lower severity files,
+	// the INFO file receives everything
+	var out [numLogTypes]io.Writer
+	for t := range out {
+		var o logDirOutput
+		for _, s := range logDirSeverities {
+			if s <= logType(t) || s == INFO {
+				o = append(o, files[s])
+			}
+		}
+		out[t] = o
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.setupLogger(out[TRACE], out[INFO], out[WARNING], out[ERROR], out[FATAL])
+
+	return nil
+}
+
+// LogDir writes the logs of the default Logger to files in dir using
+// the glog layout, see Logger.LogDir.
+func LogDir(dir string) error {
+	return std.LogDir(dir)
+}
+
+// logDirOutput is the output of a logType, it writes to the files of all
+// severities the logType is logged to
+type logDirOutput []*logDirFile
+
+func (o logDirOutput) Write(p []byte) (int, error) {
+	for _, f := range o {
+		if _, err := f.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Sync commits the files to stable storage.
+func (o logDirOutput) Sync() error {
+	var firstErr error
+	for _, f := range o {
+		if err := f.Sync(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Reopen closes the files, the next Write creates new ones.
+func (o logDirOutput) Reopen() error {
+	var firstErr error
+	for _, f := range o {
+		if err := f.Reopen(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// logDirFile is the file of one severity, it is created on the first Write
+type logDirFile struct {
+	mu       sync.Mutex
+	dir      string
+	severity logType
+	file     *os.File
+}
+
+func (f *logDirFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.create(time.Now()); err != nil {
+			return 0, err
+		}
+	}
+
+	return f.file.Write(p)
+}
+
+// Sync commits the file to stable storage.
+func (f *logDirFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Reopen closes the file, the next Write creates a new one.
+func (f *logDirFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+
+	return err
+}
+
+// create opens the file, writes the glog header and updates the symlink, f.mu must be held.
+func (f *logDirFile) create(t time.Time) error {
+
+	name, link := logName(severities[f.severity], t)
+	path := filepath.Join(f.dir, name)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("cloudglog: cannot create log: %v", err)
+	}
+	f.file = file
+
+	// the symlink is a convenience, ignore errors
+	symlink := filepath.Join(f.dir, link)
+	os.Remove(symlink)
+	os.Symlink(name, symlink)
+
+	fmt.Fprintf(file, "Log file created at: %s\n", t.Format("2006/01/02 15:04:05"))
+	fmt.Fprintf(file, "Running on machine: %s\n", host)
+	fmt.Fprintf(file, "Binary: Built with %s %s for %s/%s\n", runtime.Compiler, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(file, "Log line format: PREFIX: yyyy/mm/dd hh:mm:ss file:line: msg\n")
+
+	return nil
+}
+
+var (
+	pid      = os.Getpid()
+	program  = filepath.Base(os.Args[0])
+	host     = "unknownhost"
+	userName = "unknownuser"
+)
+
+func init() {
+
+	if h, err := os.Hostname(); err == nil {
+		host = shortHostname(h)
+	}
+
+	if u, err := user.Current(); err == nil {
+		userName = u.Username
+	}
+
+	// sanitize characters that are not allowed in file names
+	userName = strings.Replace(userName, `\`, "_", -1)
+}
+
+// shortHostname returns its argument, truncating at the first period.
+func shortHostname(hostname string) string {
+	if i := strings.Index(hostname, "."); i >= 0 {
+		return hostname[:i]
+	}
+	return hostname
+}
+
+// logName returns the file name and the symlink name for severity
+// and time t as glog names them.
+func logName(severity string, t time.Time) (name, link string) {
+	name = fmt.Sprintf("%s.%s.%s.log.%s.%04d%02d%02d-%02d%02d%02d.%d",
+		program,
+		host,
+		userName,
+		severity,
+		t.Year(),
+		t.Month(),
+		t.Day(),
+		t.Hour(),
+		t.Minute(),
+		t.Second(),
+		pid)
+	return name, program + "." + severity
+}
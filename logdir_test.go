@@ -0,0 +1,58 @@
+package cloudglog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LogDir(t *testing.T) {
+
+	dir := t.TempDir()
+	l := New(ioutil.Discard, DefaultFormat, NoColor, 0)
+	assert.NoError(t, l.LogDir(dir))
+
+	l.Info("info line")
+	l.Warning("warning line")
+	l.Error("error line")
+
+	read := func(severity string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, program+"."+severity))
+		assert.NoError(t, err, "no %s symlink", severity)
+		return string(b)
+	}
+
+	info := read("INFO")
+	assert.True(t, strings.HasPrefix(info, "Log file created at: "), "missing header in %q", info)
+	assert.Contains(t, info, "info line")
+	assert.Contains(t, info, "warning line")
+	assert.Contains(t, info, "error line")
+
+	warning := read("WARNING")
+	assert.NotContains(t, warning, "info line")
+	assert.Contains(t, warning, "warning line")
+	assert.Contains(t, warning, "error line")
+
+	errors := read("ERROR")
+	assert.NotContains(t, errors, "warning line")
+	assert.Contains(t, errors, "error line")
+
+	// no FATAL was logged, so there is no FATAL file
+	_, err := os.Lstat(filepath.Join(dir, program+".FATAL"))
+	assert.True(t, os.IsNotExist(err))
+
+	target, err := os.Readlink(filepath.Join(dir, program+".ERROR"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(target, program+"."+host+"."+userName+".log.ERROR."), "unexpected file name %q", target)
+	assert.True(t, strings.HasSuffix(target, "."+strconv.Itoa(pid)), "unexpected file name %q", target)
+}
+
+func Test_ShortHostname(t *testing.T) {
+	assert.Equal(t, "host", shortHostname("host.example.com"))
+	assert.Equal(t, "host", shortHostname("host"))
+}
@@ -0,0 +1,615 @@
+package cloudglog
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Logger is a leveled logger that carries its own outputs, format,
+// color style and verbosity, so several components of one binary can
+// log independently. The package level functions use a default Logger.
+type Logger struct {
+	*settings
+	fields []Field // attached to every record, see With
+}
+
+// settings are shared between a Logger and the Loggers derived from it
+type settings struct {
+	mu sync.Mutex
+
+	out        [numLogTypes]io.Writer // output per logType
+	stderr     io.Writer              // os.Stderr, replaced in tests
+	threshold  logType                // logTypes at or above are also written to stderr
+	format     formatStyle
+	color      colorStyle
+	fileLength int          // log.Llongfile or log.Lshortfile
+	verbosity  int32        // level for V() type calls, accessed atomically
+	trace      int32        // 1 if the TRACE log is enabled, accessed atomically
+	vmodule    atomic.Value // *vmodule, see SetVModule
+	slog       atomic.Value // slogRoute, see SetSlogHandler
+}
+
+// New creates a Logger that writes to out using the given format, color
+// style and V() level. The TRACE log is disabled, see EnableTrace.
+func New(out io.Writer, format formatStyle, cStyle colorStyle, level int) *Logger {
+	l := &Logger{settings: &settings{
+		stderr:     os.Stderr,
+		threshold:  stderrOff,
+		format:     format,
+		color:      cStyle,
+		fileLength: log.Llongfile,
+		verbosity:  int32(level),
+	}}
+	l.setupLogger(out, out, out, out, out)
+	return l
+}
+
+// Default returns the Logger the package level functions log through.
+func Default() *Logger {
+	return std
+}
+
+// setupLogger sets the output for each logType, l.mu must be held.
+func (l *Logger) setupLogger(
+	traceHandle io.Writer,
+	infoHandle io.Writer,
+	warningHandle io.Writer,
+	errorHandle io.Writer,
+	fatalHandle io.Writer) {
+
+	l.out = [numLogTypes]io.Writer{
+		TRACE:   traceHandle,
+		INFO:    infoHandle,
+		WARNING: warningHandle,
+		ERROR:   errorHandle,
+		FATAL:   fatalHandle,
+	}
+}
+
+// SetOutput sets the writer for the logType t, other logTypes keep theirs.
+// A nil w discards the records of t, a t that is not one of the logTypes
+// is ignored.
+func (l *Logger) SetOutput(t logType, w io.Writer) {
+	if t < TRACE || t > FATAL {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out[t] = w
+}
+
+// SetStderrThreshold makes records at or above t also go to stderr, unless
+// stderr already is their output. A threshold above FATAL turns it off,
+// which is the default.
+func (l *Logger) SetStderrThreshold(t logType) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.threshold = t
+}
+
+// FormatStyle changes the formatStyle
+func (l *Logger) FormatStyle(f formatStyle) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format = f
+}
+
+// ColorsStyle defines the coloring format
+func (l *Logger) ColorsStyle(cStyle colorStyle) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.color = cStyle
+}
+
+// LogFile sets the logfile to write to
+func (l *Logger) LogFile(file io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.setupLogger(file, file, file, file, file)
+}
+
+// LogFileName will log only file names
+func (l *Logger) LogFileName() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fileLength = log.Lshortfile
+}
+
+// LogFilePath will log path and file name, this is the default
+func (l *Logger) LogFilePath() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fileLength = log.Llongfile
+}
+
+// SetLogLevel sets the level for V() type calls.
+func (l *Logger) SetLogLevel(level int) {
+	atomic.StoreInt32(&l.verbosity, int32(level))
+}
+
+// GetLogLevel returns the level for V() type calls.
+func (l *Logger) GetLogLevel() int {
+	if l.settings == std.settings {
+		takeLogLevel()
+	}
+	return int(atomic.LoadInt32(&l.verbosity))
+}
+
+// output writes s and the fields of l plus fields to the log of type t,
+// depth is the runtime.Caller skip as seen from output.
+func (l *Logger) output(t logType, depth int, s string, fields ...Field) {
+	l.outputStack(t, depth+1, s, nil, fields)
+}
+
+// outputPrint is output for arguments handled in the manner of fmt.Print,
+// they are formatted into a pooled buffer instead of a new string.
+func (l *Logger) outputPrint(t logType, depth int, args []interface{}) {
+	m := getBuffer()
+	m.b = fmt.Append(m.b, args...)
+	l.outputStack(t, depth+1, bytesToString(m.b), nil, nil)
+	putBuffer(m)
+}
+
+// outputPrintln is outputPrint in the manner of fmt.Println
+func (l *Logger) outputPrintln(t logType, depth int, args []interface{}) {
+	m := getBuffer()
+	m.b = fmt.Appendln(m.b, args...)
+	l.outputStack(t, depth+1, bytesToString(m.b), nil, nil)
+	putBuffer(m)
+}
+
+// outputPrintf is outputPrint in the manner of fmt.Printf
+func (l *Logger) outputPrintf(t logType, depth int, format string, args []interface{}) {
+	m := getBuffer()
+	m.b = fmt.Appendf(m.b, format, args...)
+	l.outputStack(t, depth+1, bytesToString(m.b), nil, nil)
+	putBuffer(m)
+}
+
+// fatalOutput is output for the Fatal calls, the record carries the stacks
+// of all goroutines as glog writes them.
+func (l *Logger) fatalOutput(depth int, s string, fields ...Field) {
+	l.outputStack(FATAL, depth+1, s, stacks(true), fields)
+}
+
+// outputStack writes a record with stack, depth is the runtime.Caller skip
+// as seen from outputStack.
+func (l *Logger) outputStack(t logType, depth int, s string, stack []byte, fields []Field) {
+
+	// skip runtime.Callers itself
+	var pc [1]uintptr
+	runtime.Callers(depth+1, pc[:])
+
+	if h := l.slogHandler(); h != nil {
+		l.writeSlog(h, t, time.Now(), pc[0], nil, s, stack, fields)
+		return
+	}
+
+	r := getRecord()
+	r.Time = time.Now()
+	r.Level = t
+	r.Message = s
+	r.Fields = l.fields
+	r.Stack = stack
+	r.pc = pc[0]
+	if len(fields) > 0 {
+		r.Fields = append(r.Fields[:len(r.Fields):len(r.Fields)], fields...)
+	}
+
+	l.write(r)
+	putRecord(r)
+}
+
+// write formats r and writes it to the output of its logType
+// and to stderr if it is at or above the stderr threshold
+func (l *Logger) write(r *Record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := getBuffer()
+	b.b = l.formatRecord(b.b, r)
+	// a nil output discards the record
+	out := l.out[r.Level]
+	if out != nil {
+		out.Write(b.b)
+	}
+
+	if r.Level >= l.threshold && !sameWriter(out, l.stderr) {
+		l.stderr.Write(b.b)
+	}
+	putBuffer(b)
+}
+
+// LogRecord writes r with the format, color style and outputs of l, it
+// is meant for bridges that forward the entries of other logging
+// libraries. The fields of l are put before r.Fields and a zero Time is
+// taken as now, the caller is set with Record.SetCaller. A Level below
+// TRACE is logged as TRACE, one above FATAL as FATAL. A FATAL record
+// is written like any other, LogRecord never exits. The handler of
+// SetSlogHandler gets the time of r and a caller set with SetCaller as
+// the slog.SourceKey attribute.
+func (l *Logger) LogRecord(r Record) {
+
+	r.Level = clampLogType(r.Level)
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	if h := l.slogHandler(); h != nil {
+		// a caller set without pc is passed as attribute
+		var src *slog.Source
+		if r.pc == 0 && r.resolved {
+			file, line, function := r.Caller()
+			src = &slog.Source{Function: function, File: file, Line: line}
+		}
+		l.writeSlog(h, r.Level, r.Time, r.pc, src, r.Message, r.Stack, r.Fields)
+		return
+	}
+
+	if len(l.fields) > 0 {
+		r.Fields = append(l.fields[:len(l.fields):len(l.fields)], r.Fields...)
+	}
+
+	l.write(&r)
+}
+
+// AppendRecord appends r rendered with the format and color style of l
+// to buf, with the fields of l put before r.Fields. Use it for bridges
+// that write the rendered record themselves. The Level is limited to
+// TRACE..FATAL as in LogRecord.
+func (l *Logger) AppendRecord(buf []byte, r Record) []byte {
+
+	r.Level = clampLogType(r.Level)
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if len(l.fields) > 0 {
+		r.Fields = append(l.fields[:len(l.fields):len(l.fields)], r.Fields...)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.formatRecord(buf, &r)
+}
+
+// clampLogType limits t to TRACE..FATAL
+func clampLogType(t logType) logType {
+	switch {
+	case t < TRACE:
+		return TRACE
+	case t > FATAL:
+		return FATAL
+	}
+	return t
+}
+
+// sameWriter reports whether a and b are the same writer, it does not
+// panic on writers of uncomparable types
+func sameWriter(a, b io.Writer) bool {
+	ta := reflect.TypeOf(a)
+	return ta != nil && ta == reflect.TypeOf(b) && ta.Comparable() && a == b
+}
+
+// Info logs to the INFO log.
+// Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
+func (l *Logger) Info(args ...interface{}) {
+	l.outputPrint(INFO, CallDepth, args)
+}
+
+// InfoDepth acts as Info but uses depth to determine which call frame to log.
+// InfoDepth(0, "msg") is the same as Info("msg").
+func (l *Logger) InfoDepth(depth int, args ...interface{}) {
+	l.outputPrint(INFO, CallDepth+depth, args)
+}
+
+// Infoln logs to the INFO log.
+// Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
+func (l *Logger) Infoln(args ...interface{}) {
+	l.outputPrintln(INFO, CallDepth, args)
+}
+
+// Infof logs to the INFO log.
+// Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.outputPrintf(INFO, CallDepth, format, args)
+}
+
+// Warning logs to the WARNING log.
+// Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
+func (l *Logger) Warning(args ...interface{}) {
+	l.outputPrint(WARNING, CallDepth, args)
+}
+
+// WarningDepth acts as Warning but uses depth to determine which call frame to log.
+// WarningDepth(0, "msg") is the same as Warning("msg").
+func (l *Logger) WarningDepth(depth int, args ...interface{}) {
+	l.outputPrint(WARNING, CallDepth+depth, args)
+}
+
+// Warningln logs to the WARNING log.
+// Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
+func (l *Logger) Warningln(args ...interface{}) {
+	l.outputPrintln(WARNING, CallDepth, args)
+}
+
+// Warningf logs to the WARNING log.
+// Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
+func (l *Logger) Warningf(format string, args ...interface{}) {
+	l.outputPrintf(WARNING, CallDepth, format, args)
+}
+
+// Error logs to the ERROR log.
+// Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
+func (l *Logger) Error(args ...interface{}) {
+	l.outputPrint(ERROR, CallDepth, args)
+}
+
+// ErrorDepth acts as Error but uses depth to determine which call frame to log.
+// ErrorDepth(0, "msg") is the same as Error("msg").
+func (l *Logger) ErrorDepth(depth int, args ...interface{}) {
+	l.outputPrint(ERROR, CallDepth+depth, args)
+}
+
+// Errorln logs to the ERROR log.
+// Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
+func (l *Logger) Errorln(args ...interface{}) {
+	l.outputPrintln(ERROR, CallDepth, args)
+}
+
+// Errorf logs to the ERROR log.
+// Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.outputPrintf(ERROR, CallDepth, format, args)
+}
+
+// Fatal logs to the FATAL log including the stacks of all goroutines, then
+// flushes, runs the exit hooks and calls the exit function, os.Exit(1) by
+// default, see SetExitFunc.
+// Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
+func (l *Logger) Fatal(args ...interface{}) {
+	l.fatalOutput(CallDepth, fmt.Sprint(args...))
+	l.exit()
+}
+
+// FatalDepth acts as Fatal but uses depth to determine which call frame to log.
+// FatalDepth(0, "msg") is the same as Fatal("msg").
+func (l *Logger) FatalDepth(depth int, args ...interface{}) {
+	l.fatalOutput(CallDepth+depth, fmt.Sprint(args...))
+	l.exit()
+}
+
+// Fatalln logs to the FATAL log including the stacks of all goroutines, then
+// flushes, runs the exit hooks and calls the exit function, os.Exit(1) by
+// default, see SetExitFunc.
+// Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
+func (l *Logger) Fatalln(args ...interface{}) {
+	l.fatalOutput(CallDepth, fmt.Sprintln(args...))
+	l.exit()
+}
+
+// Fatalf logs to the FATAL log including the stacks of all goroutines, then
+// flushes, runs the exit hooks and calls the exit function, os.Exit(1) by
+// default, see SetExitFunc.
+// Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.fatalOutput(CallDepth, fmt.Sprintf(format, args...))
+	l.exit()
+}
+
+// Exit logs to the FATAL log without stacks, then flushes, runs the exit
+// hooks and calls the exit function, os.Exit(1) by default, see SetExitFunc.
+// Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
+func (l *Logger) Exit(args ...interface{}) {
+	l.outputPrint(FATAL, CallDepth, args)
+	l.exit()
+}
+
+// ExitDepth acts as Exit but uses depth to determine which call frame to log.
+// ExitDepth(0, "msg") is the same as Exit("msg").
+func (l *Logger) ExitDepth(depth int, args ...interface{}) {
+	l.outputPrint(FATAL, CallDepth+depth, args)
+	l.exit()
+}
+
+// Exitln logs to the FATAL log without stacks, then flushes, runs the exit
+// hooks and calls the exit function, os.Exit(1) by default, see SetExitFunc.
+func (l *Logger) Exitln(args ...interface{}) {
+	l.outputPrintln(FATAL, CallDepth, args)
+	l.exit()
+}
+
+// Exitf logs to the FATAL log without stacks, then flushes, runs the exit
+// hooks and calls the exit function, os.Exit(1) by default, see SetExitFunc.
+// Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
+func (l *Logger) Exitf(format string, args ...interface{}) {
+	l.outputPrintf(FATAL, CallDepth, format, args)
+	l.exit()
+}
+
+// Verbose is the Logger counterpart of Verbosity, it implements Info,
+// Infoln and Infof etc. writing to the Logger it was created from.
+// See the documentation of V for more information.
+type Verbose struct {
+	enabled bool
+	l       *Logger
+}
+
+// V reports whether the verbosity of l at the call site is at least the
+// requested level, see SetVModule. Use Enabled on the result to guard expensive arguments:
+//
+//	if l.V(2).Enabled() { l.Info("log this") }
+//
+// or
+//
+//	l.V(2).Info("log this")
+func (l *Logger) V(level int) Verbose {
+	return Verbose{enabled: l.GetLogLevel() >= level || l.vmoduleLevel(0) >= level, l: l}
+}
+
+// VDepth acts as V but uses depth to determine which call frame to check
+// vmodule against. VDepth(0, level) is the same as V(level).
+func (l *Logger) VDepth(depth, level int) Verbose {
+	return Verbose{enabled: l.GetLogLevel() >= level || l.vmoduleLevel(depth) >= level, l: l}
+}
+
+// Enabled reports whether logging at this level is turned on.
+func (v Verbose) Enabled() bool {
+	return v.enabled
+}
+
+// Info is equivalent to Logger.Info, guarded by the value of v.
+func (v Verbose) Info(args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrint(INFO, CallDepth, args)
+	}
+}
+
+// InfoDepth is equivalent to Logger.InfoDepth, guarded by the value of v.
+func (v Verbose) InfoDepth(depth int, args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrint(INFO, CallDepth+depth, args)
+	}
+}
+
+// Infoln is equivalent to Logger.Infoln, guarded by the value of v.
+func (v Verbose) Infoln(args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrintln(INFO, CallDepth, args)
+	}
+}
+
+// Infof is equivalent to Logger.Infof, guarded by the value of v.
+func (v Verbose) Infof(format string, args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrintf(INFO, CallDepth, format, args)
+	}
+}
+
+// Warning is equivalent to Logger.Warning, guarded by the value of v.
+func (v Verbose) Warning(args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrint(WARNING, CallDepth, args)
+	}
+}
+
+// WarningDepth is equivalent to Logger.WarningDepth, guarded by the value of v.
+func (v Verbose) WarningDepth(depth int, args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrint(WARNING, CallDepth+depth, args)
+	}
+}
+
+// Warningln is equivalent to Logger.Warningln, guarded by the value of v.
+func (v Verbose) Warningln(args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrintln(WARNING, CallDepth, args)
+	}
+}
+
+// Warningf is equivalent to Logger.Warningf, guarded by the value of v.
+func (v Verbose) Warningf(format string, args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrintf(WARNING, CallDepth, format, args)
+	}
+}
+
+// Error is equivalent to Logger.Error, guarded by the value of v.
+func (v Verbose) Error(args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrint(ERROR, CallDepth, args)
+	}
+}
+
+// ErrorDepth is equivalent to Logger.ErrorDepth, guarded by the value of v.
+func (v Verbose) ErrorDepth(depth int, args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrint(ERROR, CallDepth+depth, args)
+	}
+}
+
+// Errorln is equivalent to Logger.Errorln, guarded by the value of v.
+func (v Verbose) Errorln(args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrintln(ERROR, CallDepth, args)
+	}
+}
+
+// Errorf is equivalent to Logger.Errorf, guarded by the value of v.
+func (v Verbose) Errorf(format string, args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrintf(ERROR, CallDepth, format, args)
+	}
+}
+
+// Fatal is equivalent to Logger.Fatal, guarded by the value of v.
+func (v Verbose) Fatal(args ...interface{}) {
+	if v.enabled {
+		v.l.fatalOutput(CallDepth, fmt.Sprint(args...))
+		v.l.exit()
+	}
+}
+
+// FatalDepth is equivalent to Logger.FatalDepth, guarded by the value of v.
+func (v Verbose) FatalDepth(depth int, args ...interface{}) {
+	if v.enabled {
+		v.l.fatalOutput(CallDepth+depth, fmt.Sprint(args...))
+		v.l.exit()
+	}
+}
+
+// Fatalln is equivalent to Logger.Fatalln, guarded by the value of v.
+func (v Verbose) Fatalln(args ...interface{}) {
+	if v.enabled {
+		v.l.fatalOutput(CallDepth, fmt.Sprintln(args...))
+		v.l.exit()
+	}
+}
+
+// Fatalf is equivalent to Logger.Fatalf, guarded by the value of v.
+func (v Verbose) Fatalf(format string, args ...interface{}) {
+	if v.enabled {
+		v.l.fatalOutput(CallDepth, fmt.Sprintf(format, args...))
+		v.l.exit()
+	}
+}
+
+// Exit is equivalent to Logger.Exit, guarded by the value of v.
+func (v Verbose) Exit(args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrint(FATAL, CallDepth, args)
+		v.l.exit()
+	}
+}
+
+// ExitDepth is equivalent to Logger.ExitDepth, guarded by the value of v.
+func (v Verbose) ExitDepth(depth int, args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrint(FATAL, CallDepth+depth, args)
+		v.l.exit()
+	}
+}
+
+// Exitln is equivalent to Logger.Exitln, guarded by the value of v.
+func (v Verbose) Exitln(args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrintln(FATAL, CallDepth, args)
+		v.l.exit()
+	}
+}
+
+// Exitf is equivalent to Logger.Exitf, guarded by the value of v.
+func (v Verbose) Exitf(format string, args ...interface{}) {
+	if v.enabled {
+		v.l.outputPrintf(FATAL, CallDepth, format, args)
+		v.l.exit()
+	}
+}
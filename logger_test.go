@@ -0,0 +1,190 @@
+package cloudglog
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LoggerInstances(t *testing.T) {
+
+	var a, b bytes.Buffer
+
+	la := New(&a, DefaultFormat, NoColor, 0)
+	lb := New(&b, ModernFormat, NoColor, 2)
+
+	la.Info("first")
+	lb.Warning("second")
+
+	assert.True(t, strings.HasPrefix(a.String(), "INFO: "), "unexpected output %q", a.String())
+	assert.Contains(t, a.String(), "logger_test.go:")
+	assert.True(t, strings.HasSuffix(a.String(), ": first\n"), "unexpected output %q", a.String())
+
+	assert.True(t, strings.HasPrefix(b.String(), "WARNING: "), "unexpected output %q", b.String())
+	assert.Contains(t, b.String(), "][logger_test.go][:")
+	assert.True(t, strings.HasSuffix(b.String(), "]\t second\n"), "unexpected output %q", b.String())
+}
+
+func Test_LoggerV(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 1)
+
+	assert.True(t, l.V(1).Enabled())
+	assert.False(t, l.V(2).Enabled())
+
+	l.V(2).Info("hidden")
+	assert.Equal(t, 0, b.Len())
+
+	l.SetLogLevel(2)
+	l.V(2).Info("shown")
+	assert.True(t, strings.HasSuffix(b.String(), " shown\n"), "unexpected output %q", b.String())
+}
+
+func Test_SetOutput(t *testing.T) {
+
+	var all, errs, stderr bytes.Buffer
+	l := New(&all, DefaultFormat, NoColor, 0)
+	l.stderr = &stderr
+
+	l.SetOutput(ERROR, &errs)
+	l.Info("info")
+	l.Error("error")
+
+	assert.True(t, strings.HasSuffix(all.String(), ": info\n"), "unexpected output %q", all.String())
+	assert.True(t, strings.HasSuffix(errs.String(), ": error\n"), "unexpected output %q", errs.String())
+	assert.Equal(t, 0, stderr.Len(), "threshold is off by default")
+
+	l.SetStderrThreshold(WARNING)
+	l.Info("info")
+	l.Warning("warning")
+	assert.True(t, strings.HasPrefix(stderr.String(), "WARNING: "), "unexpected output %q", stderr.String())
+	assert.Equal(t, 1, strings.Count(stderr.String(), "\n"))
+
+	// no duplicates when stderr already is the output
+	stderr.Reset()
+	l.SetOutput(ERROR, &stderr)
+	l.Error("error")
+	assert.Equal(t, 1, strings.Count(stderr.String(), "\n"))
+}
+
+func Test_SetOutputInvalid(t *testing.T) {
+
+	var b, stderr bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+	l.stderr = &stderr
+
+	// severities outside TRACE..FATAL are ignored
+	assert.NotPanics(t, func() { l.SetOutput(7, &stderr) })
+	assert.NotPanics(t, func() { l.SetOutput(-1, &stderr) })
+	l.Info("info")
+	assert.Equal(t, 1, strings.Count(b.String(), "\n"))
+
+	// a nil writer discards the records, stderr still gets its copy
+	l.SetOutput(INFO, nil)
+	l.SetStderrThreshold(INFO)
+	b.Reset()
+	assert.NotPanics(t, func() { l.Info("discarded") })
+	assert.Equal(t, 0, b.Len())
+	assert.True(t, strings.HasSuffix(stderr.String(), " discarded\n"), "unexpected output %q", stderr.String())
+}
+
+func Test_FatalStacks(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+
+	// Fatal without the os.Exit
+	l.fatalOutput(CallDepth, "fatal")
+	lines := strings.SplitN(b.String(), "\n", 2)
+	assert.True(t, strings.HasSuffix(lines[0], ": fatal"), "unexpected output %q", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "goroutine "), "missing stacks in %q", lines[1])
+	assert.Contains(t, lines[1], "Test_FatalStacks")
+	assert.True(t, strings.HasSuffix(b.String(), "\n"))
+
+	// Exit without the os.Exit
+	b.Reset()
+	l.output(FATAL, CallDepth, "exit")
+	assert.NotContains(t, b.String(), "goroutine ")
+
+	b.Reset()
+	l.FormatStyle(JSONFormat)
+	l.fatalOutput(CallDepth, "fatal")
+	assert.Contains(t, b.String(), `"message":"fatal","stack":"goroutine `)
+	assert.Equal(t, 1, strings.Count(b.String(), "\n"))
+
+	b.Reset()
+	l.FormatStyle(CloudLoggingFormat)
+	l.fatalOutput(CallDepth, "fatal")
+	assert.Contains(t, b.String(), `,"stack_trace":"goroutine `)
+}
+
+func Test_LogRecord(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0).With("app", "api")
+
+	r := Record{Level: WARNING, Message: "forwarded", Fields: []Field{{Key: "port", Value: 80}}}
+	r.SetCaller("/src/db/conn.go", 42, "db.Open")
+	l.LogRecord(r)
+	assert.True(t, strings.HasPrefix(b.String(), "WARNING: "), "unexpected output %q", b.String())
+	assert.True(t, strings.HasSuffix(b.String(), " /src/db/conn.go:42: forwarded app=api port=80\n"), "unexpected output %q", b.String())
+
+	// the fields of the Record passed in are left alone
+	assert.Len(t, r.Fields, 1)
+
+	// without caller the file is unknown
+	b.Reset()
+	l.LogRecord(Record{Level: INFO, Message: "no caller"})
+	assert.Contains(t, b.String(), " ???:0: no caller")
+
+	buf := l.AppendRecord([]byte("> "), r)
+	assert.True(t, strings.HasPrefix(string(buf), "> WARNING: "), "unexpected output %q", buf)
+	assert.True(t, strings.HasSuffix(string(buf), " /src/db/conn.go:42: forwarded app=api port=80\n"), "unexpected output %q", buf)
+}
+
+func Test_LogRecordLevel(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+
+	// levels outside TRACE..FATAL are limited to them
+	assert.NotPanics(t, func() { l.LogRecord(Record{Level: 99, Message: "high"}) })
+	assert.True(t, strings.HasPrefix(b.String(), "Fatal: "), "unexpected output %q", b.String())
+
+	b.Reset()
+	assert.NotPanics(t, func() { l.LogRecord(Record{Level: -1, Message: "low"}) })
+	assert.True(t, strings.HasPrefix(b.String(), "TRACE: "), "unexpected output %q", b.String())
+
+	buf := l.AppendRecord(nil, Record{Level: 99, Message: "high"})
+	assert.True(t, strings.HasPrefix(string(buf), "Fatal: "), "unexpected output %q", buf)
+
+	l.SetSlogHandler(slog.NewTextHandler(&b, nil))
+	b.Reset()
+	assert.NotPanics(t, func() { l.LogRecord(Record{Level: 99, Message: "high"}) })
+	assert.Contains(t, b.String(), "level=ERROR+4 ")
+}
+
+func Test_LogRecordSlog(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(io.Discard, DefaultFormat, NoColor, 0)
+	l.SetSlogHandler(slog.NewTextHandler(&b, &slog.HandlerOptions{AddSource: true}))
+
+	// the time and caller of the record are kept
+	r := Record{Level: WARNING, Message: "forwarded", Time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	r.SetCaller("/src/db/conn.go", 42, "db.Open")
+	l.LogRecord(r)
+	assert.Contains(t, b.String(), "time=2020-01-02T03:04:05.000Z level=WARN ")
+	assert.Contains(t, b.String(), " source=/src/db/conn.go:42")
+
+	// without caller there is no source
+	b.Reset()
+	l.LogRecord(Record{Level: INFO, Message: "no caller"})
+	assert.NotContains(t, b.String(), "source=")
+}
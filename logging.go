@@ -1,5 +1,5 @@
 // Package cloudglog is a logger that outputs to stdout. It is strongly based on glog but
-// without any kind of buffering.
+// without any kind of buffering, unless it is asked for with AsyncWriter.
 //
 // LogFile
 //
@@ -16,6 +16,37 @@
 //
 //    cloudglog.LogFile(w)
 //
+// SetOutput sets the writer of a single severity and SetStderrThreshold copies
+// records at or above a severity to stderr, like glog's -stderrthreshold:
+//
+//    cloudglog.SetOutput(cloudglog.WARNING, os.Stderr)
+//    cloudglog.SetStderrThreshold(cloudglog.ERROR)
+//
+// RotatingFile rolls the file over on size or time and keeps a number of backups:
+//
+//    cloudglog.LogFile(&cloudglog.RotatingFile{
+//        Filename:   "/var/log/app.log",
+//        MaxSize:    100 << 20,
+//        Every:      cloudglog.RotateDaily,
+//        MaxBackups: 7,
+//        Compress:   true,
+//    })
+//
+// AsyncWriter queues records and writes them on a background goroutine, so a
+// slow output does not block the callers, when the queue is full it blocks,
+// drops the newest or drops the oldest record:
+//
+//    a := cloudglog.NewAsyncWriter(os.Stdout, 4096, cloudglog.AsyncDropOldest)
+//    defer a.Close()
+//    cloudglog.LogFile(a)
+//
+// LogDir writes one file per severity in the layout of glog's -log_dir,
+// program.host.user.log.SEVERITY.yyyymmdd-hhmmss.pid with program.SEVERITY symlinks:
+//
+//    if err := cloudglog.LogDir("/var/log/app"); err != nil {
+//        cloudglog.Fatal(err)
+//    }
+//
 //
 // Format Styles
 //
@@ -23,6 +54,8 @@
 //
 //  DefaultFormat		: the original glog format
 //  ModernFormat		: shorter format, uses brackets to separate Package, File, Line
+//  JSONFormat		: one JSON object per line, for log pipelines
+//  CloudLoggingFormat	: Google Cloud Logging structured JSON, see CloudTrace, CloudSpanID and CloudLabels
 //
 // Example:
 //  cloudglog.FormatStyle(cloudglog.ModernFormat)
@@ -42,14 +75,178 @@
 // Example:
 //  cloudglog.ColorStyle(cloudglog.FullColor)
 //
+// Formatter
+//
+// custom output layouts implement the Formatter interface and are
+// installed with RegisterFormat, which returns the style for FormatStyle.
+//
+// Example:
+//  csv := cloudglog.RegisterFormat("csv", myCSVFormatter{})
+//  cloudglog.FormatStyle(csv)
+//
+// Performance
+//
+// log calls format into pooled buffers and resolve call sites once, Info, Infof
+// and friends do not allocate with the built in formats, V() and TRACE calls
+// that are off return before formatting. The benchmarks show the allocations
+// per call for each format and color style:
+//
+//  go test -run NONE -bench . -benchmem
+//
+// Logger
+//
+// use New to create a Logger with its own output, format, color style and
+// verbosity, the package level functions log through a default Logger.
+//
+// Example:
+//  l := cloudglog.New(os.Stdout, cloudglog.ModernFormat, cloudglog.FullColor, 2)
+//  l.V(2).Info("log this")
+//
+// Fields
+//
+// key/value pairs can be attached with With or passed to the Infow type calls,
+// text formats render them as key=value after the message. In the JSON formats
+// a field replaces earlier fields with the same key, keys the format writes
+// itself, like "message", and keys starting with "fields." get a "fields."
+// prefix.
+//
+// Example:
+//  cloudglog.With("user", id).Infow("request done", "req", rid)
+//
+// Context
+//
+// NewContext attaches fields to a context.Context, the Ctx variants like
+// InfoCtx log them on every record and FromContext returns the Logger
+// carrying them. VCtx checks the level of that Logger, V(level).InfofCtx
+// checks the level of the default Logger.
+//
+// Example:
+//  ctx = cloudglog.NewContext(r.Context(), "req", rid, "tenant", tenant)
+//  cloudglog.VCtx(ctx, 2).Infof("loaded %d items", n)
+//
+// OpenTelemetry
+//
+// the cloudotel package adds the trace id, span id and sampled flag of the
+// span of the context to the records of the Ctx functions and SlogHandler.
+// CloudLoggingFormat writes them as the logging.googleapis.com/trace keys,
+// qualified with the project of SetCloudProject or GOOGLE_CLOUD_PROJECT.
+//
+// Example:
+//  cloudotel.Register()
+//  cloudglog.InfoCtx(ctx, "charged") // ... charged trace_id=4bf9... span_id=00f0... trace_sampled=true
+//
+// Fatal and Exit
+//
+// both log to the FATAL log and call os.Exit(1), Fatal also writes the stacks
+// of all goroutines like glog, as a "stack" member in JSONFormat and as
+// "stack_trace" for Error Reporting in CloudLoggingFormat. Exit writes no stacks.
+//
+// Before exiting the outputs are flushed, a bufio.Writer passed to LogFile
+// does not lose its buffer, and the hooks registered with RegisterExitHook
+// run, bounded by SetExitTimeout.
+//
+// Example:
+//  cloudglog.RegisterExitHook(tracer.Close)
+//
+// SetExitFunc replaces os.Exit and CaptureExit returns the output and exit code
+// of code that calls Fatal or Exit, so it can be tested in-process.
+//
+// Example:
+//  out, code := cloudglog.CaptureExit(func() { loadConfig("missing.yaml") })
+//
+// Admin Endpoint
+//
+// Handler returns an http.Handler that shows the V() level, vmodule, TRACE
+// log, format and color style as JSON on GET and changes them on PUT or POST.
+//
+// Example:
+//  http.Handle("/debug/loglevel", cloudglog.Handler())
+//
+//  curl -X PUT 'localhost:8080/debug/loglevel?level=2&vmodule=server=3'
+//
+// Signals
+//
+// HandleSignals is opt-in, SIGUSR1 raises the V() level by one, SIGUSR2 restores
+// it and SIGHUP reopens the log files for logrotate's move and signal workflow.
+// Reopen reaches files, RotatingFile and AsyncWriter but not a file behind a
+// bufio.Writer, it reports those outputs as an error.
+//
+// Example:
+//  defer cloudglog.HandleSignals()()
+//
+// VModule
+//
+// raises the V() level for single files or packages like glog's -vmodule,
+// it can also be set by the LOG_VMODULE environment variable.
+//
+// Example:
+//  cloudglog.SetVModule("server=3,db/*=2")
+//
+// Trace
+//
+// the TRACE log is off by default and its calls cost no formatting while it is,
+// turn it on with EnableTrace(true) or LOG_LEVEL=trace.
+//
+// Example:
+//  cloudglog.EnableTrace(true)
+//  cloudglog.Tracef("payload %q", body)
+//
+// slog
+//
+// NewSlogHandler returns a slog.Handler that writes with the format and color
+// style of a Logger, SetSlogHandler routes the log functions through any
+// slog.Handler instead.
+//
+// Example:
+//  slog.SetDefault(slog.New(cloudglog.NewSlogHandler(l, nil)))
+//  cloudglog.SetSlogHandler(slog.NewJSONHandler(os.Stderr, nil))
+//
+// logr
+//
+// the cloudlogr package provides a logr.LogSink for libraries that log with
+// go-logr, Default returns the Logger of the package level functions.
+//
+// Example:
+//  ctrl.SetLogger(cloudlogr.New(cloudglog.Default()))
+//
+// zap and logrus
+//
+// the cloudzap package provides a zapcore.Core and the cloudlogrus package a
+// logrus Hook and Formatter that forward entries with their level, caller and
+// fields, Logger.LogRecord writes such records for other bridges.
+//
+// Example:
+//  z := zap.New(cloudzap.NewCore(cloudglog.Default(), zapcore.DebugLevel), zap.AddCaller())
+//  logrus.AddHook(cloudlogrus.NewHook(cloudglog.Default()))
+//
+// Standard library log
+//
+// CaptureStdLog redirects the default logger of the log package and StdLogger
+// returns a log.Logger that logs to cloudglog. The lines are parsed, their
+// severity is taken from prefixes like "ERROR:" or "[warn]" by a set of
+// SeverityRules and the original date, time and file:line are kept.
+// StdLogWriter is the io.Writer for a log.Logger made elsewhere.
+//
+// Example:
+//  defer cloudglog.CaptureStdLog(nil)()
+//  srv.ErrorLog = cloudglog.StdLogger(cloudglog.ERROR, "", log.Lshortfile, nil)
+//
+// Writer
+//
+// returns a LineWriter, an io.Writer that logs every complete line written to
+// it to one log. Partial lines are buffered, long lines are split and the
+// records are attributed to the caller of Writer or the one set with SetCaller.
+//
+// Example:
+//  cmd.Stdout = cloudglog.Writer(cloudglog.INFO)
+//  cmd.Stderr = cloudglog.Writer(cloudglog.WARNING)
+//
 // LogFilter
 //
 // can be used to filter logging of other packages
 // that provide a way to set the log output. It takes a io.Writer
 // as output and a logType and returns a io.Writer.
 //
-// TODO: make this function more idiomatic
-//
 // Example:
 //   ERROR = log.New(cloudglog.LogFilter(os.Stdout, cloudglog.ERROR),
 //  	"ERROR: ",
@@ -58,20 +255,40 @@
 package cloudglog
 
 import (
-	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"log"
 	"os"
 	"strconv"
 	"strings"
 	"runtime"
+	"sync/atomic"
 )
 
 const CallDepth = 2 // depth to trace the caller file
 
-var LogLevel int // logging level for V() type calls, can also be set by LOG_LEVEL environment variable
+// LogLevel is the logging level for V() type calls read from the LOG_LEVEL
+// environment variable, LOG_LEVEL=trace enables the TRACE log.
+//
+// Deprecated: the default Logger takes over an assignment to LogLevel on
+// its next V call, but assigning it races with logging and LogLevel does
+// not follow SetLogLevel. Use SetLogLevel and GetLogLevel.
+var LogLevel int
+
+// logLevelSeen is the value of LogLevel the default Logger took over last,
+// accessed atomically
+var logLevelSeen int32
+
+// takeLogLevel sets the level of the default Logger to LogLevel if it was
+// assigned since the last call
+func takeLogLevel() {
+	if level := int32(LogLevel); level != atomic.LoadInt32(&logLevelSeen) {
+		atomic.StoreInt32(&logLevelSeen, level)
+		std.SetLogLevel(int(level))
+	}
+}
+
+// std is the default Logger used by the package level functions
+var std = New(os.Stdout, DefaultFormat, NoColor, 0)
 
 type formatStyle int
 
@@ -79,44 +296,64 @@ const (
 	// formatStyle controlss the output format
 	DefaultFormat formatStyle = iota // PREFIX: YYYY/MM/DD HH:MM:SS log.Llongfile Message
 	ModernFormat                     // PREFIX: YYYY/MM/DD HH:MM:SS [Package][File][:Line] Message
+	JSONFormat                       // {"timestamp":...,"severity":...,"file":...,"line":...,"package":...,"message":...,fields}
+	CloudLoggingFormat               // {"severity":...,"message":...,"time":...,"logging.googleapis.com/sourceLocation":...,fields}
 )
 
-var currentFormat formatStyle
-
 // FormatStyle changes the formatStyle
 func FormatStyle(f formatStyle) {
-	currentFormat = f
-	setupLogger(logFile, logFile, logFile, logFile, logFile)
+	std.FormatStyle(f)
 }
 
-var logFile io.Writer = os.Stdout
-
 // LogFile sets the logfile to write to
 func LogFile(file io.Writer) {
-	logFile = file
-	setupLogger(logFile, logFile, logFile, logFile, logFile)
+	std.LogFile(file)
 }
 
-// can be set to log.Llongfile or log.Lshortfile
-var lFileLength = log.Llongfile
+// SetOutput sets the writer for the logType t, other logTypes keep theirs.
+// A nil w discards the records of t, a t that is not one of the logTypes
+// is ignored.
+//
+// Example:
+//  cloudglog.SetOutput(cloudglog.WARNING, os.Stderr)
+func SetOutput(t logType, w io.Writer) {
+	std.SetOutput(t, w)
+}
 
+// SetStderrThreshold makes records at or above t also go to stderr, unless
+// stderr already is their output. A threshold above FATAL turns it off,
+// which is the default.
+func SetStderrThreshold(t logType) {
+	std.SetStderrThreshold(t)
+}
 
 // LogFileName will log only file names
 func LogFileName() {
-	lFileLength = log.Lshortfile
+	std.LogFileName()
 }
 
 // LogFilePath will log path and file name, this is the default
 func LogFilePath() {
-	lFileLength = log.Llongfile
+	std.LogFilePath()
+}
+
+// SetLogLevel sets the level for V() type calls, it starts at the value of
+// the LOG_LEVEL environment variable. LOG_LEVEL=trace enables the TRACE log.
+// A later assignment to the deprecated LogLevel still overrides it.
+func SetLogLevel(level int) {
+	std.SetLogLevel(level)
+}
+
+// GetLogLevel returns the level for V() type calls
+func GetLogLevel() int {
+	return std.GetLogLevel()
 }
 
 
 type logType int
 
 const (
-	// logType, only used in LogFilter to set the right color
-	// TODO: while reworking LogFilter overhink this too
+	// logType is the severity of a record
 	TRACE   logType = iota // TRACE: ColorCyan
 	INFO                   // INFO: ColorGreen
 	WARNING                // WARNING: ColorYellow
@@ -124,6 +361,20 @@ const (
 	FATAL                  // FATAL: ColorMagenta
 )
 
+const numLogTypes = int(FATAL) + 1
+
+// stderrOff is a stderr threshold above all logTypes
+const stderrOff = logType(numLogTypes)
+
+// prefixes for each logType
+var prefixes = []string{
+	TRACE:   "TRACE:",
+	INFO:    "INFO:",
+	WARNING: "WARNING:",
+	ERROR:   "ERROR:",
+	FATAL:   "Fatal:",
+}
+
 type colorType int
 
 const (
@@ -152,8 +403,6 @@ const (
 )
 
 var (
-	colorFormating colorStyle = NoColor
-
 	colors = []string{
 		TRACE:   colorSeq(ColorCyan),
 		INFO:    colorSeq(ColorGreen),
@@ -179,182 +428,22 @@ func colorSeqBold(color colorType) string {
 	return fmt.Sprintf("\033[%d;1m", int(color))
 }
 
-func addColor(lType logType, prefixEnd int, message []string) []string {
-
-	var col, bcol string
-
-	switch colorFormating {
-	case PrefixColor:
-		col = colors[lType]
-		message[prefixEnd] = strings.Join([]string{message[prefixEnd], "\033[0m"}, "")
-		message = append([]string{col}, message...)
-	case PrefixBoldColor:
-		col = boldcolors[lType]
-		message[prefixEnd] = strings.Join([]string{message[prefixEnd], "\033[0m"}, "")
-		message = append([]string{col}, message...)
-	case FullColor:
-		col = colors[lType]
-		message = append([]string{col}, message...)
-		message = append(message, "\033[0m")
-	case FullBoldColor:
-		col = boldcolors[lType]
-		message = append([]string{col}, message...)
-		message = append(message, "\033[0m")
-	case FullColorWithBoldMessage:
-		col = colors[lType]
-		bcol = boldcolors[lType]
-		message[prefixEnd] = strings.Join([]string{message[prefixEnd], bcol}, "")
-		message = append([]string{col}, message...)
-		message = append(message, "\033[0m")
-	case FullColorWithBoldPrefix:
-		col = colors[lType]
-		bcol = boldcolors[lType]
-		message[prefixEnd] = strings.Join([]string{message[prefixEnd], col}, "")
-		message = append([]string{bcol}, message...)
-		message = append(message, "\033[0m")
-	}
-
-	return message
-}
-
 // ColorsStyle defines the coloring format
 func ColorsStyle(cStyle colorStyle) {
-	colorFormating = cStyle
-}
-
-var (
-	traceLog   *log.Logger
-	infoLog    *log.Logger
-	warningLog *log.Logger
-	errorLog   *log.Logger
-	fatalLog   *log.Logger
-)
-
-func setupLogger(
-	traceHandle io.Writer,
-	infoHandle io.Writer,
-	warningHandle io.Writer,
-	errorHandle io.Writer,
-	fatalHandle io.Writer) {
-
-	traceLog = log.New(LogFilter(traceHandle, TRACE),
-		"TRACE: ",
-		log.Ldate|log.Ltime|lFileLength)
-
-	infoLog = log.New(LogFilter(infoHandle, INFO),
-		"INFO: ",
-		log.Ldate|log.Ltime|lFileLength)
-
-	warningLog = log.New(LogFilter(warningHandle, WARNING),
-		"WARNING: ",
-		log.Ldate|log.Ltime|lFileLength)
-
-	errorLog = log.New(LogFilter(errorHandle, ERROR),
-		"ERROR: ",
-		log.Ldate|log.Ltime|lFileLength)
-	fatalLog = log.New(LogFilter(fatalHandle, FATAL),
-		"Fatal: ",
-		log.Ldate|log.Ltime|lFileLength)
+	std.ColorsStyle(cStyle)
 }
 
 // LogFilter can be used to filter logging of other packages
 // that provide a way to set the log output. It takes a io.Writer
-// as output and a logType and returns a io.Writer.
+// as output and a logType and returns a io.Writer. The date and time of
+// a line are only taken from its start together with a file:line: word,
+// otherwise the line is the message. StdLogWriter also infers the
+// logType of each line.
 func LogFilter(out io.Writer, l logType) io.Writer {
-
-	// TODO: make this more idiomatic
-	switch currentFormat {
-	case DefaultFormat:
-		return &defaultLogger{out: out, logType: l}
-	case ModernFormat:
-		return &modernLogger{out: out, logType: l}
-	}
-
-	return ioutil.Discard
-}
-
-type defaultLogger struct {
-	out     io.Writer
-	logType logType
+	return &filterWriter{out: out, logType: l, l: std}
 }
 
-func (d *defaultLogger) Write(bytes []byte) (int, error) {
-
-	string_ := string(bytes)
-
-	// splitFunc that splits at avery ' '
-	stringSplit := func(r rune) bool {
-		return r == ' '
-	}
-
-	// split to access Llongfile
-	format := strings.FieldsFunc(string_, stringSplit)
-
-	// find log prefix (starts with '/' and ends with ':')
-	formatByte := []byte(format[3])
-	prefixEnd := 2
-	if (formatByte[0] == '/') && (formatByte[len(formatByte)-1] == ':') {
-		prefixEnd = 3
-	}
-
-	// format color
-	format = addColor(d.logType, prefixEnd, format)
-
-	// join string
-	defaultFormat := strings.Join(format, " ")
-
-	return d.out.Write([]byte(defaultFormat))
-}
-
-type modernLogger struct {
-	out     io.Writer
-	logType logType
-}
-
-// TODO: check efficiency and maybe reimplement in []byte operations
-func (m *modernLogger) Write(bytes []byte) (int, error) {
-
-	string_ := string(bytes)
-
-	// splitFunc that splits at avery ' '
-	stringSplit := func(r rune) bool {
-		return r == ' '
-	}
-
-	// split to access Llongfile
-	format := strings.FieldsFunc(string_, stringSplit)
-
-	// find log prefix (starts with '/' and ends with ':')
-	formatByte := []byte(format[3])
-	prefixEnd := 2
-	if (formatByte[0] == '/') && (formatByte[len(formatByte)-1] == ':') {
-		prefixEnd = 3
-	}
-
-	// splitFunc for log.Llongfile
-	longFileSplit := func(r rune) bool {
-		return r == '/' || r == ':'
-	}
-	var modernLongFile = make([]string, 3)
-	// split log.Llongfile
-	subFormat := strings.FieldsFunc(format[prefixEnd], longFileSplit)
-	copy(modernLongFile, subFormat[len(subFormat)-3:]) // package, file, line
-
-	// add []'s and a trailing tab
-	format[prefixEnd] = strings.Join([]string{"[", modernLongFile[0], "]", "[", modernLongFile[1], "]", "[:", modernLongFile[2], "]", "\t"}, "")
-
-	// format color
-	format = addColor(m.logType, prefixEnd, format)
-
-	// join string
-	modernFormat := strings.Join(format, " ")
-
-	return m.out.Write([]byte(modernFormat))
-}
-
-
 // stacks is a wrapper for runtime.Stack that attempts to recover the data for all goroutines.
-// Todo: wire this func into fatal and panic
 func stacks(all bool) []byte {
 	// We don't know how big the traces are, so grow a few times if they don't fit. Start large, though.
 	n := 10000
@@ -375,11 +464,19 @@ func stacks(all bool) []byte {
 
 func init() {
 
+	std.mu.Lock()
+	std.setupLogger(os.Stdout, os.Stdout, os.Stdout, os.Stderr, os.Stderr)
+	std.mu.Unlock()
+
 	// get LogLevel from env
 	getLogLevel := os.Getenv("LOG_LEVEL")
 	if len(getLogLevel) == 0 {
 		// loglevel is not in env, set to default
 		LogLevel = 0
+	} else if strings.EqualFold(getLogLevel, "trace") {
+		// trace turns on the TRACE log and keeps the default level
+		LogLevel = 0
+		EnableTrace(true)
 	} else {
 		// loglevel is a string, convert it to int
 		var err error
@@ -391,161 +488,151 @@ func init() {
 		}
 
 	}
+	takeLogLevel()
 
-	currentFormat = DefaultFormat // init with DefaultFormat
-	setupLogger(ioutil.Discard, os.Stdout, os.Stdout, os.Stderr, os.Stderr)
+	// get vmodule from env
+	if err := SetVModule(os.Getenv("LOG_VMODULE")); err != nil {
+		Errorf("reading vmodule from environment variable: %v", err)
+	}
+
+	// project of the traces in CloudLoggingFormat
+	SetCloudProject(os.Getenv("GOOGLE_CLOUD_PROJECT"))
 }
 
 
 // Info logs to the INFO log.
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Info(args ...interface{}) {
-	infoLog.Output(CallDepth, fmt.Sprint(args...))
+	std.outputPrint(INFO, CallDepth, args)
 }
 
 // InfoDepth acts as Info but uses depth to determine which call frame to log.
 // InfoDepth(0, "msg") is the same as Info("msg").
 func InfoDepth(depth int, args ...interface{}) {
-	infoLog.Output(depth, fmt.Sprint(args...))
+	std.outputPrint(INFO, CallDepth+depth, args)
 }
 
 // Infoln logs to the INFO log.
 // Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
 func Infoln(args ...interface{}) {
-	infoLog.Output(CallDepth, fmt.Sprintln(args...))
+	std.outputPrintln(INFO, CallDepth, args)
 }
 
 // Infof logs to the INFO log.
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Infof(format string, args ...interface{}) {
-
-	var buf bytes.Buffer
-	fmt.Fprintf(&buf, format, args...)
-	if buf.Bytes()[buf.Len()-1] != '\n' {
-		buf.WriteByte('\n')
-	}
-	infoLog.Output(CallDepth, buf.String())
+	std.outputPrintf(INFO, CallDepth, format, args)
 }
 
 // Warning logs to the WARNING log.
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Warning(args ...interface{}) {
-	warningLog.Output(CallDepth, fmt.Sprint(args...))
+	std.outputPrint(WARNING, CallDepth, args)
 }
 
 // WarningDepth acts as WARNING but uses depth to determine which call frame to log.
 // WarningDepth(0, "msg") is the same as Warning("msg").
 func WarningDepth(depth int, args ...interface{}) {
-	warningLog.Output(depth, fmt.Sprint(args...))
+	std.outputPrint(WARNING, CallDepth+depth, args)
 }
 
 // Warningln logs to the WARNING log.
 // Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
 func Warningln(args ...interface{}) {
-	warningLog.Output(CallDepth, fmt.Sprintln(args...))
+	std.outputPrintln(WARNING, CallDepth, args)
 }
 
 // Warningf logs to the WARNING log.
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Warningf(format string, args ...interface{}) {
-	warningLog.Output(CallDepth, fmt.Sprintf(format, args...))
+	std.outputPrintf(WARNING, CallDepth, format, args)
 }
 
 // Error logs to the ERROR log.
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Error(args ...interface{}) {
-	errorLog.Output(CallDepth, fmt.Sprint(args...))
+	std.outputPrint(ERROR, CallDepth, args)
 }
 
 // ErrorDepth acts as ERROR but uses depth to determine which call frame to log.
 // ErrorDepth(0, "msg") is the same as Error("msg").
 func ErrorDepth(depth int, args ...interface{}) {
-	errorLog.Output(depth, fmt.Sprint(args...))
+	std.outputPrint(ERROR, CallDepth+depth, args)
 }
 
 // Errorln logs to the ERROR log.
 // Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
 func Errorln(args ...interface{}) {
-	errorLog.Output(CallDepth, fmt.Sprintln(args...))
+	std.outputPrintln(ERROR, CallDepth, args)
 }
 
 // Errorf logs to the ERROR log.
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Errorf(format string, args ...interface{}) {
-
-	var buf bytes.Buffer
-	fmt.Fprintf(&buf, format, args...)
-	if buf.Bytes()[buf.Len()-1] != '\n' {
-		buf.WriteByte('\n')
-	}
-	errorLog.Output(CallDepth, buf.String())
+	std.outputPrintf(ERROR, CallDepth, format, args)
 }
 
-// Fatal logs to the FATAL log
+// Fatal logs to the FATAL log including the stacks of all goroutines, then
+// flushes, runs the exit hooks and calls the exit function, os.Exit(1) by
+// default, see SetExitFunc.
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Fatal(args ...interface{}) {
-	fatalLog.Output(CallDepth, fmt.Sprint(args...))
-	// Todo: check if we need to flush here.
-	os.Exit(1)
+	std.fatalOutput(CallDepth, fmt.Sprint(args...))
+	std.exit()
 }
 
 // FatalDepth acts as FATAL but uses depth to determine which call frame to log.
 // FatalDepth(0, "msg") is the same as Fatal("msg").
 func FatalDepth(depth int, args ...interface{}) {
-	fatalLog.Output(depth, fmt.Sprint(args...))
-	os.Exit(1)
+	std.fatalOutput(CallDepth+depth, fmt.Sprint(args...))
+	std.exit()
 }
 
-// Fatalln logs to the FATAL log.
+// Fatalln logs to the FATAL log including the stacks of all goroutines, then
+// flushes, runs the exit hooks and calls the exit function, os.Exit(1) by
+// default, see SetExitFunc.
 func Fatalln(args ...interface{}) {
-	fatalLog.Output(CallDepth, fmt.Sprintln(args...))
-	os.Exit(1)
+	std.fatalOutput(CallDepth, fmt.Sprintln(args...))
+	std.exit()
 }
 
-// Fatalf logs to the FATAL log.
+// Fatalf logs to the FATAL log including the stacks of all goroutines, then
+// flushes, runs the exit hooks and calls the exit function, os.Exit(1) by
+// default, see SetExitFunc.
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Fatalf(format string, args ...interface{}) {
-
-	var buf bytes.Buffer
-	fmt.Fprintf(&buf, format, args...)
-	if buf.Bytes()[buf.Len()-1] != '\n' {
-		buf.WriteByte('\n')
-	}
-	fatalLog.Output(CallDepth, buf.String())
-	os.Exit(1)
+	std.fatalOutput(CallDepth, fmt.Sprintf(format, args...))
+	std.exit()
 }
 
-// Exit logs to the FATAL, ERROR, WARNING, and INFO logs, then calls os.Exit(1).
+// Exit logs to the FATAL log without stacks, then flushes, runs the exit
+// hooks and calls the exit function, os.Exit(1) by default, see SetExitFunc.
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Exit(args ...interface{}) {
-	fatalLog.Output(CallDepth, fmt.Sprint(args...))
-	os.Exit(1)
+	std.outputPrint(FATAL, CallDepth, args)
+	std.exit()
 }
 
 // ExitDepth acts as Exit but uses depth to determine which call frame to log.
 // ExitDepth(0, "msg") is the same as Exit("msg").
 func ExitDepth(depth int, args ...interface{}) {
-	fatalLog.Output(depth, fmt.Sprint(args...))
-	os.Exit(1)
+	std.outputPrint(FATAL, CallDepth+depth, args)
+	std.exit()
 }
 
-// Exitln logs to the FATAL, ERROR, WARNING, and INFO logs, then calls os.Exit(1).
+// Exitln logs to the FATAL log without stacks, then flushes, runs the exit
+// hooks and calls the exit function, os.Exit(1) by default, see SetExitFunc.
 func Exitln(args ...interface{}) {
-	fatalLog.Output(CallDepth, fmt.Sprintln(args...))
-	os.Exit(1)
+	std.outputPrintln(FATAL, CallDepth, args)
+	std.exit()
 }
 
-// Exitf logs to the FATAL, ERROR, WARNING, and INFO logs, then calls os.Exit(1).
+// Exitf logs to the FATAL log without stacks, then flushes, runs the exit
+// hooks and calls the exit function, os.Exit(1) by default, see SetExitFunc.
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Exitf(format string, args ...interface{}) {
-
-	var buf bytes.Buffer
-	fmt.Fprintf(&buf, format, args...)
-	if buf.Bytes()[buf.Len()-1] != '\n' {
-		buf.WriteByte('\n')
-	}
-	fatalLog.Output(CallDepth, buf.String())
-	os.Exit(1)
+	std.outputPrintf(FATAL, CallDepth, format, args)
+	std.exit()
 }
 
 // Verbosity is a boolean type that implements Infof (like Printf) etc.
@@ -566,18 +653,29 @@ func V(level int) Verbosity {
 	// The fast path is two atomic loads and compares.
 
 	// Here is a cheap but safe test to see if V logging is enabled globally.
-	if LogLevel >= level {
+	if std.GetLogLevel() >= level {
 		return Verbosity(true)
 	}
 
-	return Verbosity(false)
+	// It's off globally but vmodule may still be set, the level of the
+	// call site is cached by its program counter.
+	return Verbosity(std.vmoduleLevel(0) >= level)
+}
+
+// VDepth acts as V but uses depth to determine which call frame to check
+// vmodule against. VDepth(0, level) is the same as V(level).
+func VDepth(depth, level int) Verbosity {
+	if std.GetLogLevel() >= level {
+		return Verbosity(true)
+	}
+	return Verbosity(std.vmoduleLevel(depth) >= level)
 }
 
 // Info is equivalent to the global Info function, guarded by the value of v.
 // See the documentation of V for usage.
 func (v Verbosity) Info(args ...interface{}) {
 	if v {
-		infoLog.Output(CallDepth+1, fmt.Sprint(args...))
+		std.outputPrint(INFO, CallDepth, args)
 	}
 }
 
@@ -585,7 +683,7 @@ func (v Verbosity) Info(args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) InfoDepth(depth int, args ...interface{}) {
 	if v {
-		infoLog.Output(depth, fmt.Sprint(args...))
+		std.outputPrint(INFO, CallDepth+depth, args)
 	}
 }
 
@@ -593,7 +691,7 @@ func (v Verbosity) InfoDepth(depth int, args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) Infoln(args ...interface{}) {
 	if v {
-		infoLog.Output(CallDepth+1, fmt.Sprintln(args...))
+		std.outputPrintln(INFO, CallDepth, args)
 	}
 }
 
@@ -601,12 +699,7 @@ func (v Verbosity) Infoln(args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) Infof(format string, args ...interface{}) {
 	if v {
-		var buf bytes.Buffer
-		fmt.Fprintf(&buf, format, args...)
-		if buf.Bytes()[buf.Len()-1] != '\n' {
-			buf.WriteByte('\n')
-		}
-		infoLog.Output(CallDepth+1, buf.String())
+		std.outputPrintf(INFO, CallDepth, format, args)
 	}
 }
 
@@ -614,7 +707,7 @@ func (v Verbosity) Infof(format string, args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) Warning(args ...interface{}) {
 	if v {
-		warningLog.Output(CallDepth+1, fmt.Sprint(args...))
+		std.outputPrint(WARNING, CallDepth, args)
 	}
 }
 
@@ -622,7 +715,7 @@ func (v Verbosity) Warning(args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) WarningDepth(depth int, args ...interface{}) {
 	if v {
-		warningLog.Output(depth, fmt.Sprint(args...))
+		std.outputPrint(WARNING, CallDepth+depth, args)
 	}
 }
 
@@ -630,7 +723,7 @@ func (v Verbosity) WarningDepth(depth int, args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) Warningln(args ...interface{}) {
 	if v {
-		warningLog.Output(CallDepth+1, fmt.Sprintln(args...))
+		std.outputPrintln(WARNING, CallDepth, args)
 	}
 }
 
@@ -638,7 +731,7 @@ func (v Verbosity) Warningln(args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) Warningf(format string, args ...interface{}) {
 	if v {
-		warningLog.Output(CallDepth+1, fmt.Sprintf(format, args...))
+		std.outputPrintf(WARNING, CallDepth, format, args)
 	}
 }
 
@@ -646,7 +739,7 @@ func (v Verbosity) Warningf(format string, args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) Error(args ...interface{}) {
 	if v {
-		errorLog.Output(CallDepth+1, fmt.Sprint(args...))
+		std.outputPrint(ERROR, CallDepth, args)
 	}
 }
 
@@ -654,7 +747,7 @@ func (v Verbosity) Error(args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) ErrorDepth(depth int, args ...interface{}) {
 	if v {
-		errorLog.Output(depth, fmt.Sprint(args...))
+		std.outputPrint(ERROR, CallDepth+depth, args)
 	}
 }
 
@@ -662,7 +755,7 @@ func (v Verbosity) ErrorDepth(depth int, args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) Errorln(args ...interface{}) {
 	if v {
-		errorLog.Output(CallDepth+1, fmt.Sprintln(args...))
+		std.outputPrintln(ERROR, CallDepth, args)
 	}
 }
 
@@ -670,49 +763,47 @@ func (v Verbosity) Errorln(args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) Errorf(format string, args ...interface{}) {
 	if v {
-		var buf bytes.Buffer
-		fmt.Fprintf(&buf, format, args...)
-		if buf.Bytes()[buf.Len()-1] != '\n' {
-			buf.WriteByte('\n')
-		}
-		errorLog.Output(CallDepth+1, buf.String())
+		std.outputPrintf(ERROR, CallDepth, format, args)
 	}
 }
 
-// Fatal is equivalent to the global Fatal function, guarded by the value of v.
+// Fatal is equivalent to the global Fatal function, guarded by the value of v:
+// it logs including the stacks of all goroutines, flushes and exits.
 // See the documentation of V for usage.
 func (v Verbosity) Fatal(args ...interface{}) {
 	if v {
-		fatalLog.Output(CallDepth+1, fmt.Sprint(args...))
+		std.fatalOutput(CallDepth, fmt.Sprint(args...))
+		std.exit()
 	}
 }
 
-// FatalDepth is equivalent to the global FatalDepth function, guarded by the value of v.
+// FatalDepth is equivalent to the global FatalDepth function, guarded by the value of v:
+// it logs including the stacks of all goroutines, flushes and exits.
 // See the documentation of V for usage.
 func (v Verbosity) FatalDepth(depth int, args ...interface{}) {
 	if v {
-		fatalLog.Output(depth, fmt.Sprint(args...))
+		std.fatalOutput(CallDepth+depth, fmt.Sprint(args...))
+		std.exit()
 	}
 }
 
-// Fatalln is equivalent to the global Fatalln function, guarded by the value of v.
+// Fatalln is equivalent to the global Fatalln function, guarded by the value of v:
+// it logs including the stacks of all goroutines, flushes and exits.
 // See the documentation of V for usage.
 func (v Verbosity) Fatalln(args ...interface{}) {
 	if v {
-		fatalLog.Output(CallDepth+1, fmt.Sprintln(args...))
+		std.fatalOutput(CallDepth, fmt.Sprintln(args...))
+		std.exit()
 	}
 }
 
-// Fatalf is equivalent to the global Fatalf function, guarded by the value of v.
+// Fatalf is equivalent to the global Fatalf function, guarded by the value of v:
+// it logs including the stacks of all goroutines, flushes and exits.
 // See the documentation of V for usage.
 func (v Verbosity) Fatalf(format string, args ...interface{}) {
 	if v {
-		var buf bytes.Buffer
-		fmt.Fprintf(&buf, format, args...)
-		if buf.Bytes()[buf.Len()-1] != '\n' {
-			buf.WriteByte('\n')
-		}
-		fatalLog.Output(CallDepth+1, buf.String())
+		std.fatalOutput(CallDepth, fmt.Sprintf(format, args...))
+		std.exit()
 	}
 }
 
@@ -720,17 +811,17 @@ func (v Verbosity) Fatalf(format string, args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) Exit(args ...interface{}) {
 	if v {
-		fatalLog.Output(CallDepth+1, fmt.Sprint(args...))
-		os.Exit(1)
+		std.outputPrint(FATAL, CallDepth, args)
+		std.exit()
 	}
 }
 
-// c is equivalent to the global Exitln function, guarded by the value of v.
+// ExitDepth is equivalent to the global ExitDepth function, guarded by the value of v.
 // See the documentation of V for usage.
 func (v Verbosity) ExitDepth(depth int, args ...interface{}) {
 	if v {
-		fatalLog.Output(depth, fmt.Sprint(args...))
-		os.Exit(1)
+		std.outputPrint(FATAL, CallDepth+depth, args)
+		std.exit()
 	}
 }
 
@@ -738,21 +829,16 @@ func (v Verbosity) ExitDepth(depth int, args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbosity) Exitln(args ...interface{}) {
 	if v {
-		fatalLog.Output(CallDepth+1, fmt.Sprintln(args...))
-		os.Exit(1)
+		std.outputPrintln(FATAL, CallDepth, args)
+		std.exit()
 	}
 }
 
-// Exitf is equivalent to the global Exitf  function, guarded by the value of v.
+// Exitf is equivalent to the global Exitf function, guarded by the value of v.
 // See the documentation of V for usage.
 func (v Verbosity) Exitf(format string, args ...interface{}) {
 	if v {
-		var buf bytes.Buffer
-		fmt.Fprintf(&buf, format, args...)
-		if buf.Bytes()[buf.Len()-1] != '\n' {
-			buf.WriteByte('\n')
-		}
-		fatalLog.Output(CallDepth+1, buf.String())
-		os.Exit(1)
+		std.outputPrintf(FATAL, CallDepth, format, args)
+		std.exit()
 	}
 }
@@ -53,19 +53,18 @@ var logCases = []logCase{
 		args: []interface{}{"Errorln"},
 		expected: " Errorln\n",
 	},
-	//{
-	// TODO: test os.Exit(1)
-	//	name: "Fatal",
-	//	logFunc: Fatal,
-	//	args: []interface{}{"Fatal"},
-	//	expected: " Fatal\n",
-	//},
-	//{
-	//	name: "Fatalln",
-	//	logFunc: Fatalln,
-	//	args: []interface{}{"Fatalln"},
-	//	expected: " Fatalln\n",
-	//},
+	{
+		name: "Fatal",
+		logFunc: Fatal,
+		args: []interface{}{"Fatal"},
+		expected: " Fatal\n",
+	},
+	{
+		name: "Fatalln",
+		logFunc: Fatalln,
+		args: []interface{}{"Fatalln"},
+		expected: " Fatalln\n",
+	},
 }
 
 func Test_LogFile(t *testing.T) {
@@ -75,6 +74,11 @@ func Test_LogFile(t *testing.T) {
 
 	LogFile(writer)
 
+	// Fatal returns instead of exiting
+	var code int
+	SetExitFunc(func(c int) { code = c })
+	defer SetExitFunc(nil)
+
 	for _, testCase := range logCases {
 
 		testCase.logFunc(testCase.args...)
@@ -82,8 +86,30 @@ func Test_LogFile(t *testing.T) {
 
 		t.Log(string(b.Bytes()))
 
-		assert.Equal(t, testCase.expected, strings.Split(string(b.Bytes()), ":")[5], "%s wrong assertion for %s", t.Name(), testCase.name)
+		// the first line, Fatal is followed by the stacks
+		line := strings.SplitAfter(string(b.Bytes()), "\n")[0]
+
+		assert.Equal(t, testCase.expected, strings.Split(line, ":")[5], "%s wrong assertion for %s", t.Name(), testCase.name)
 
 		b.Reset()
 	}
-}
\ No newline at end of file
+
+	assert.Equal(t, 1, code)
+}
+func Test_LogLevel(t *testing.T) {
+
+	defer SetLogLevel(GetLogLevel())
+	defer func(level int) { LogLevel = level; takeLogLevel() }(LogLevel)
+
+	// an assignment is taken over by the next V
+	LogLevel = 3
+	assert.True(t, bool(V(3)))
+	assert.Equal(t, 3, GetLogLevel())
+
+	// SetLogLevel wins until LogLevel is assigned again
+	SetLogLevel(1)
+	assert.False(t, bool(V(3)))
+	LogLevel = 0
+	assert.False(t, bool(V(1)))
+	assert.Equal(t, 0, Default().GetLogLevel())
+}
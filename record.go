@@ -0,0 +1,457 @@
+package cloudglog
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const colorReset = "\033[0m"
+
+// Record is a single log call as it is handed to a Formatter. Records are
+// reused, a Record and its Message must not be retained after Format returns.
+type Record struct {
+	Time    time.Time
+	Level   logType
+	Message string  // may end in a newline
+	Fields  []Field // of the Logger and the call
+	Stack   []byte  // goroutine dump of a Fatal call, nil otherwise
+
+	// settings of the Logger, formatters may ignore them
+	Color      colorStyle
+	FileLength int // log.Llongfile or log.Lshortfile
+
+	pc uintptr // program counter of the caller, 0 if unknown
+
+	// file, line and function are resolved from pc on first use
+	resolved bool
+	file     string
+	line     int
+	function string
+}
+
+// Caller returns file, line and function of the log call, an unknown
+// file is returned as "???".
+func (r *Record) Caller() (file string, line int, function string) {
+
+	if !r.resolved {
+		r.resolved = true
+		if r.pc != 0 {
+			f := callerFrame(r.pc)
+			r.file, r.line, r.function = f.file, f.line, f.function
+		}
+		if r.file == "" {
+			r.file = "???"
+		}
+	}
+
+	return r.file, r.line, r.function
+}
+
+// SetCaller sets the file, line and function of the log call, for records
+// built by bridges that know the caller from another logging library.
+func (r *Record) SetCaller(file string, line int, function string) {
+	r.pc = 0
+	r.resolved = true
+	r.file, r.line, r.function = file, line, function
+	if r.file == "" {
+		r.file = "???"
+	}
+}
+
+// frame is the resolved position of a log call site
+type frame struct {
+	file     string
+	line     int
+	function string
+}
+
+// callerFrames caches the frames of the log call sites
+var callerFrames sync.Map // uintptr -> frame
+
+// callerFrame resolves the program counter pc of a log call site
+func callerFrame(pc uintptr) frame {
+
+	if f, ok := callerFrames.Load(pc); ok {
+		return f.(frame)
+	}
+
+	fr, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	f := frame{fr.File, fr.Line, fr.Function}
+	callerFrames.Store(pc, f)
+
+	return f
+}
+
+// appendText appends the record in one of the text formats
+//
+//	DefaultFormat	PREFIX: YYYY/MM/DD HH:MM:SS log.Llongfile Message
+//	ModernFormat	PREFIX: YYYY/MM/DD HH:MM:SS [Package][File][:Line] Message
+//
+// colored by cStyle, the prefix ends after the file position.
+func (r *Record) appendText(buf []byte, modern bool) []byte {
+
+	cStyle := r.Color
+
+	col, bcol := colors[r.Level], boldcolors[r.Level]
+
+	switch cStyle {
+	case PrefixColor, FullColor, FullColorWithBoldMessage:
+		buf = append(buf, col...)
+	case PrefixBoldColor, FullBoldColor, FullColorWithBoldPrefix:
+		buf = append(buf, bcol...)
+	}
+
+	buf = append(buf, prefixes[r.Level]...)
+	buf = append(buf, ' ')
+	buf = r.Time.AppendFormat(buf, "2006/01/02 15:04:05")
+	buf = append(buf, ' ')
+
+	file, line, _ := r.Caller()
+	if modern {
+		dir, name := path.Split(file)
+		buf = append(buf, '[')
+		buf = append(buf, path.Base(dir)...)
+		buf = append(buf, "]["...)
+		buf = append(buf, name...)
+		buf = append(buf, "][:"...)
+		buf = strconv.AppendInt(buf, int64(line), 10)
+		buf = append(buf, "]\t"...)
+	} else {
+		if r.FileLength == log.Lshortfile {
+			file = path.Base(file)
+		}
+		buf = append(buf, file...)
+		buf = append(buf, ':')
+		buf = strconv.AppendInt(buf, int64(line), 10)
+		buf = append(buf, ':')
+	}
+
+	switch cStyle {
+	case PrefixColor, PrefixBoldColor:
+		buf = append(buf, colorReset...)
+	case FullColorWithBoldMessage:
+		buf = append(buf, bcol...)
+	case FullColorWithBoldPrefix:
+		buf = append(buf, col...)
+	}
+
+	buf = append(buf, ' ')
+	buf = r.appendMessage(buf)
+
+	switch cStyle {
+	case FullColor, FullBoldColor, FullColorWithBoldMessage, FullColorWithBoldPrefix:
+		buf = append(buf, colorReset...)
+	}
+	buf = append(buf, '\n')
+
+	// the goroutine dump follows the line as glog writes it
+	if len(r.Stack) > 0 {
+		buf = append(buf, r.Stack...)
+		if r.Stack[len(r.Stack)-1] != '\n' {
+			buf = append(buf, '\n')
+		}
+	}
+
+	return buf
+}
+
+// appendMessage appends the message without trailing newline followed
+// by the fields as key=value pairs
+func (r *Record) appendMessage(buf []byte) []byte {
+
+	buf = append(buf, strings.TrimSuffix(r.Message, "\n")...)
+
+	for _, f := range r.Fields {
+		buf = append(buf, ' ')
+		buf = append(buf, f.Key...)
+		buf = append(buf, '=')
+		buf = appendValue(buf, f.Value)
+	}
+
+	return buf
+}
+
+// appendValue appends v, quoted if it would be ambiguous in a key=value list
+func appendValue(buf []byte, v interface{}) []byte {
+
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" {
+		return append(buf, `""`...)
+	}
+
+	for _, c := range s {
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			return strconv.AppendQuote(buf, s)
+		}
+	}
+
+	return append(buf, s...)
+}
+
+// filterWriter turns lines written by a log.Logger of another package into
+// records, see LogFilter.
+type filterWriter struct {
+	out     io.Writer
+	logType logType
+	l       *Logger
+}
+
+func (f *filterWriter) Write(bytes []byte) (int, error) {
+
+	r := parseLine(string(bytes), f.logType, "", -1)
+
+	f.l.mu.Lock()
+	defer f.l.mu.Unlock()
+
+	b := getBuffer()
+	defer putBuffer(b)
+
+	b.b = f.l.formatRecord(b.b, &r)
+	if _, err := f.out.Write(b.b); err != nil {
+		return 0, err
+	}
+
+	return len(bytes), nil
+}
+
+// parseLine builds a record of type t from a line rendered by a log.Logger
+// with prefix and flags, see parseHeader. Negative flags are unknown, see
+// guessHeader. A line whose header cannot be taken apart is kept whole as
+// the message.
+func parseLine(s string, t logType, prefix string, flags int) Record {
+
+	r := Record{Time: time.Now(), Level: t, resolved: true, file: "???"}
+	s = strings.TrimSuffix(s, "\n")
+
+	var h lineHeader
+	var ok bool
+	if flags < 0 {
+		h, ok = guessHeader(s)
+	} else {
+		h, ok = parseHeader(s, prefix, flags)
+	}
+	if !ok {
+		r.Message = s
+		return r
+	}
+
+	if !h.time.IsZero() {
+		r.Time = h.time
+	}
+	if h.file != "" {
+		r.file, r.line = h.file, h.line
+	}
+
+	// keep prefixes that are not the severity of t
+	msg := h.message
+	if p := strings.TrimSpace(h.prefix); p != "" && !strings.EqualFold(strings.TrimSuffix(p, ":"), severities[t]) {
+		msg = p + " " + msg
+	}
+
+	r.Message = msg
+	return r
+}
+
+// lineHeader is the header of a line of a log.Logger taken apart
+type lineHeader struct {
+	prefix  string
+	time    time.Time // zero if the line has none
+	file    string    // "" if the line has none
+	line    int
+	message string // the rest of the line
+}
+
+// parseHeader takes apart the header of s as a log.Logger with prefix and
+// flags writes it, it fails if s does not have that header.
+func parseHeader(s, prefix string, flags int) (h lineHeader, ok bool) {
+
+	if flags&log.Lmsgprefix == 0 {
+		if !strings.HasPrefix(s, prefix) {
+			return h, false
+		}
+		h.prefix, s = prefix, s[len(prefix):]
+	}
+
+	loc := time.Local
+	if flags&log.LUTC != 0 {
+		loc = time.UTC
+	}
+
+	var date, clock string
+	if flags&log.Ldate != 0 {
+		if !matchPattern(s, "dddd/dd/dd ") {
+			return h, false
+		}
+		date, s = s[:10], s[11:]
+	}
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		pattern := "dd:dd:dd"
+		if flags&log.Lmicroseconds != 0 {
+			pattern = "dd:dd:dd.dddddd"
+		}
+		if !matchPattern(s, pattern+" ") {
+			return h, false
+		}
+		clock, s = s[:len(pattern)], s[len(pattern)+1:]
+	}
+
+	// a date without clock leaves the time of the record alone
+	if clock != "" {
+		if date == "" {
+			date = time.Now().In(loc).Format("2006/01/02")
+		}
+		ts, err := time.ParseInLocation("2006/01/02 15:04:05.999999", date+" "+clock, loc)
+		if err != nil {
+			return h, false
+		}
+		h.time = ts
+	}
+
+	if flags&(log.Llongfile|log.Lshortfile) != 0 {
+		word, rest, _ := strings.Cut(s, " ")
+		file, line, ok := splitPosition(word)
+		if !ok {
+			return h, false
+		}
+		h.file, h.line, s = file, line, rest
+	}
+
+	if flags&log.Lmsgprefix != 0 {
+		if !strings.HasPrefix(s, prefix) {
+			return h, false
+		}
+		h.prefix, s = prefix, s[len(prefix):]
+	}
+
+	h.message = s
+	return h, true
+}
+
+// guessHeader takes apart the header of a line of a log.Logger whose prefix
+// and flags are unknown. The date and time are looked for at the start of s
+// or after a first prefix word and only taken if a file:line: word follows
+// them, so times in the message stay where they are.
+func guessHeader(s string) (h lineHeader, ok bool) {
+
+	starts := []int{0}
+	if i := strings.IndexByte(s, ' '); i > 0 {
+		starts = append(starts, i+1)
+	}
+
+	for _, i := range starts {
+		rest := s[i:]
+		ts, n := parseTimestamp(rest)
+		rest = strings.TrimPrefix(rest[n:], " ")
+
+		word, msg, _ := strings.Cut(rest, " ")
+		file, line, ok := splitPosition(word)
+		if !ok {
+			continue
+		}
+
+		return lineHeader{prefix: s[:i], time: ts, file: file, line: line, message: msg}, true
+	}
+
+	return h, false
+}
+
+// parseTimestamp parses the date and time written by a log.Logger at the
+// start of s and returns them with the number of bytes consumed.
+func parseTimestamp(s string) (time.Time, int) {
+
+	date := time.Now().Format("2006/01/02")
+	n := 0
+
+	if matchPattern(s, "dddd/dd/dd") {
+		date = s[:10]
+		n = 10
+		if !matchPattern(s[n:], " dd:dd:dd") {
+			ts, err := time.ParseInLocation("2006/01/02", date, time.Local)
+			if err != nil {
+				return time.Time{}, 0
+			}
+			return ts, n
+		}
+		n++
+	}
+
+	if !matchPattern(s[n:], "dd:dd:dd") {
+		return time.Time{}, 0
+	}
+
+	clock := s[n : n+8]
+	if matchPattern(s[n+8:], ".dddddd") {
+		clock = s[n : n+15]
+	}
+	n += len(clock)
+
+	ts, err := time.ParseInLocation("2006/01/02 15:04:05.999999", date+" "+clock, time.Local)
+	if err != nil {
+		return time.Time{}, 0
+	}
+
+	return ts, n
+}
+
+// matchPattern reports whether s starts with pattern, a 'd' in pattern matches a digit
+func matchPattern(s, pattern string) bool {
+
+	if len(s) < len(pattern) {
+		return false
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == 'd' {
+			if s[i] < '0' || s[i] > '9' {
+				return false
+			}
+		} else if s[i] != pattern[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitPosition splits a file:line: word into its parts
+func splitPosition(word string) (string, int, bool) {
+
+	if len(word) < 4 || word[len(word)-1] != ':' {
+		return "", 0, false
+	}
+
+	word = word[:len(word)-1]
+	i := strings.LastIndexByte(word, ':')
+	if i <= 0 {
+		return "", 0, false
+	}
+
+	line, err := strconv.Atoi(word[i+1:])
+	if err != nil || line < 0 {
+		return "", 0, false
+	}
+
+	return word[:i], line, true
+}
@@ -0,0 +1,133 @@
+package cloudglog
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRecord() Record {
+	return Record{
+		Time:     time.Date(2017, 1, 2, 15, 4, 5, 0, time.Local),
+		Level:    INFO,
+		Message:  "msg  with  spaces\n",
+		Fields:   []Field{{Key: "k", Value: "v"}},
+		resolved: true,
+		file:     "/src/pkg/file.go",
+		line:     12,
+	}
+}
+
+func Test_AppendText(t *testing.T) {
+
+	r := testRecord()
+	col, bcol := colors[INFO], boldcolors[INFO]
+
+	cases := []struct {
+		format   formatStyle
+		color    colorStyle
+		length   int
+		expected string
+	}{
+		{DefaultFormat, NoColor, log.Llongfile, "INFO: 2017/01/02 15:04:05 /src/pkg/file.go:12: msg  with  spaces k=v\n"},
+		{DefaultFormat, NoColor, log.Lshortfile, "INFO: 2017/01/02 15:04:05 file.go:12: msg  with  spaces k=v\n"},
+		{ModernFormat, NoColor, log.Llongfile, "INFO: 2017/01/02 15:04:05 [pkg][file.go][:12]\t msg  with  spaces k=v\n"},
+		{DefaultFormat, PrefixColor, log.Lshortfile, col + "INFO: 2017/01/02 15:04:05 file.go:12:" + colorReset + " msg  with  spaces k=v\n"},
+		{DefaultFormat, PrefixBoldColor, log.Lshortfile, bcol + "INFO: 2017/01/02 15:04:05 file.go:12:" + colorReset + " msg  with  spaces k=v\n"},
+		{DefaultFormat, FullColor, log.Lshortfile, col + "INFO: 2017/01/02 15:04:05 file.go:12: msg  with  spaces k=v" + colorReset + "\n"},
+		{DefaultFormat, FullBoldColor, log.Lshortfile, bcol + "INFO: 2017/01/02 15:04:05 file.go:12: msg  with  spaces k=v" + colorReset + "\n"},
+		{DefaultFormat, FullColorWithBoldMessage, log.Lshortfile, col + "INFO: 2017/01/02 15:04:05 file.go:12:" + bcol + " msg  with  spaces k=v" + colorReset + "\n"},
+		{DefaultFormat, FullColorWithBoldPrefix, log.Lshortfile, bcol + "INFO: 2017/01/02 15:04:05 file.go:12:" + col + " msg  with  spaces k=v" + colorReset + "\n"},
+	}
+
+	for _, c := range cases {
+		r.Color, r.FileLength = c.color, c.length
+		assert.Equal(t, c.expected, string(r.appendText(nil, c.format == ModernFormat)))
+	}
+}
+
+func Test_ParseLine(t *testing.T) {
+
+	cases := []struct {
+		line    string
+		prefix  string
+		flags   int // -1 if unknown
+		file    string
+		lineNo  int
+		message string
+	}{
+		// unknown flags need a file:line: word after the date and time
+		{"ERROR: 2017/01/02 15:04:05 /a/b/c.go:7: boom\n", "", -1, "/a/b/c.go", 7, "boom"},
+		{"ERROR: 2017/01/02 15:04:05 c.go:7: boom\n", "", -1, "c.go", 7, "boom"},
+		{"2017/01/02 15:04:05.123456 c.go:7: boom boom\n", "", -1, "c.go", 7, "boom boom"},
+		{"[lib] c.go:7: boom\n", "", -1, "c.go", 7, "[lib] boom"},
+		{"[lib] 15:04:05 boom\n", "", -1, "???", 0, "[lib] 15:04:05 boom"},
+		{"2017/01/02 x\n", "", -1, "???", 0, "2017/01/02 x"},
+		{"retry at 10:00:00 failed\n", "", -1, "???", 0, "retry at 10:00:00 failed"},
+		{"job started at 12:30:00 c.go:7: ok\n", "", -1, "???", 0, "job started at 12:30:00 c.go:7: ok"},
+		{"x\n", "", -1, "???", 0, "x"},
+		{"\n", "", -1, "???", 0, ""},
+		{"", "", -1, "???", 0, ""},
+
+		// known prefix and flags
+		{"retry at 10:00:00 failed\n", "", 0, "???", 0, "retry at 10:00:00 failed"},
+		{"10:00:00 backup started\n", "", 0, "???", 0, "10:00:00 backup started"},
+		{"[lib] 15:04:05 boom\n", "[lib] ", log.Ltime, "???", 0, "[lib] boom"},
+		{"[lib] 15:04:05 at 10:00:00\n", "[lib] ", log.Ltime, "???", 0, "[lib] at 10:00:00"},
+		{"2017/01/02 15:04:05 ", "", log.LstdFlags, "???", 0, ""},
+		{"2017/01/02 15:04:05.123456 c.go:7: boom\n", "", log.LstdFlags | log.Lmicroseconds | log.Lshortfile, "c.go", 7, "boom"},
+		{"c.go:7: E! boom\n", "E! ", log.Lshortfile | log.Lmsgprefix, "c.go", 7, "E! boom"},
+		{"ERROR: 15:04:05 boom\n", "ERROR: ", log.Ltime, "???", 0, "boom"},
+
+		// a header that does not match the flags keeps the line whole
+		{"2017/01/02 boom\n", "", log.LstdFlags, "???", 0, "2017/01/02 boom"},
+		{"15:04:05 boom\n", "[lib] ", log.Ltime, "???", 0, "15:04:05 boom"},
+		{"15:04:05 boom\n", "", log.Ltime | log.Lshortfile, "???", 0, "15:04:05 boom"},
+	}
+
+	for _, c := range cases {
+		r := parseLine(c.line, ERROR, c.prefix, c.flags)
+		file, line, _ := r.Caller()
+		assert.Equal(t, c.file, file, "file of %q", c.line)
+		assert.Equal(t, c.lineNo, line, "line of %q", c.line)
+		assert.Equal(t, c.message, r.Message, "message of %q", c.line)
+	}
+
+	// the time of the header is taken
+	r := parseLine("2017/01/02 15:04:05 boom", INFO, "", log.LstdFlags|log.LUTC)
+	assert.Equal(t, time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC), r.Time)
+
+	r = parseLine("2017/01/02 15:04:05 c.go:7: boom", INFO, "", -1)
+	assert.Equal(t, time.Date(2017, 1, 2, 15, 4, 5, 0, time.Local), r.Time)
+
+	// times in the message are left alone
+	before := time.Now()
+	r = parseLine("retry at 10:00:00 failed", INFO, "", 0)
+	assert.False(t, r.Time.Before(before), "time taken from the message")
+}
+
+func Test_LogFilter(t *testing.T) {
+
+	var b bytes.Buffer
+	lg := log.New(LogFilter(&b, ERROR), "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
+
+	lg.Print("boom")
+	assert.True(t, strings.HasPrefix(b.String(), "ERROR: "), "unexpected output %q", b.String())
+	assert.Contains(t, b.String(), " record_test.go:")
+	assert.True(t, strings.HasSuffix(b.String(), ": boom\n"), "unexpected output %q", b.String())
+
+	// lines without any header must not panic
+	b.Reset()
+	_, err := LogFilter(&b, ERROR).Write([]byte("a"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(b.String(), " ???:0: a\n"), "unexpected output %q", b.String())
+
+	// times in the message of a logger without flags stay in the message
+	b.Reset()
+	log.New(LogFilter(&b, INFO), "", 0).Print("job started at 12:30:00 ok")
+	assert.True(t, strings.HasSuffix(b.String(), " ???:0: job started at 12:30:00 ok\n"), "unexpected output %q", b.String())
+}
@@ -0,0 +1,125 @@
+package cloudglog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Reopener is implemented by outputs that can close and open their file
+// again, see Logger.Reopen.
+type Reopener interface {
+	Reopen() error
+}
+
+// Reopen reopens the file outputs of l, so logs go to a new file after
+// logrotate moved the old one. Outputs implementing Reopener, like
+// RotatingFile and AsyncWriter, are asked to reopen. An *os.File other
+// than stdout and stderr is opened again by its name for appending, the
+// old file is only closed if Reopen opened it, a file passed in stays
+// open for its owner.
+//
+// Reopen cannot reach the file behind a bufio.Writer or another io.Writer
+// that wraps it, it returns an error for a bufio.Writer and for outputs
+// that are an io.Closer. Wrap such files in an AsyncWriter or use a
+// RotatingFile instead. Outputs in memory, like a bytes.Buffer, are left
+// alone.
+func (l *Logger) Reopen() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var firstErr error
+	var done [numLogTypes]io.Writer
+
+	for t, w := range l.out {
+
+		// outputs shared between logTypes are reopened once
+		shared := false
+		for u := 0; u < t; u++ {
+			if sameWriter(l.out[u], w) {
+				w, shared = done[u], true
+				break
+			}
+		}
+
+		if !shared {
+			nw, err := reopenWriter(w)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			w = nw
+		}
+
+		done[t] = w
+	}
+
+	// reopened files are compared against the old outputs above,
+	// replace them only at the end
+	l.out = done
+
+	return firstErr
+}
+
+// Reopen reopens the file outputs of the default Logger, see Logger.Reopen.
+func Reopen() error {
+	return std.Reopen()
+}
+
+// reopenedFiles are the files opened by reopenFile, only they are
+// closed when they are reopened again
+var reopenedFiles sync.Map // *os.File -> struct{}
+
+// reopenWriter reopens w and returns the writer to use from now on
+func reopenWriter(w io.Writer) (io.Writer, error) {
+
+	switch o := w.(type) {
+	case Reopener:
+		return w, o.Reopen()
+	case *os.File:
+		if o == os.Stdout || o == os.Stderr {
+			return w, nil
+		}
+		return reopenFile(o)
+	case *bufio.Writer:
+		return w, errors.New("cloudglog: cannot reopen the file of a bufio.Writer")
+	case io.Closer:
+		return w, fmt.Errorf("cloudglog: cannot reopen output %T", w)
+	}
+
+	return w, nil
+}
+
+// reopenFile opens the file named like old and closes old if an earlier
+// reopenFile opened it
+func reopenFile(old *os.File) (io.Writer, error) {
+
+	f, err := os.OpenFile(old.Name(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return old, err
+	}
+	reopenedFiles.Store(f, struct{}{})
+
+	if _, ok := reopenedFiles.LoadAndDelete(old); ok {
+		old.Close()
+	}
+
+	return f, nil
+}
+
+// Reopen closes the current file, the next Write opens Filename again.
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+
+	return err
+}
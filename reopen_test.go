@@ -0,0 +1,147 @@
+package cloudglog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReopenFile(t *testing.T) {
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+
+	var other bytes.Buffer
+	l := New(f, DefaultFormat, NoColor, 0)
+	l.SetOutput(ERROR, &other)
+
+	l.Info("before")
+
+	// logrotate moves the file and signals
+	assert.NoError(t, os.Rename(name, name+".1"))
+	assert.NoError(t, l.Reopen())
+
+	l.Info("after")
+	l.Warning("after")
+	l.Error("other")
+
+	old, _ := ioutil.ReadFile(name + ".1")
+	assert.Contains(t, string(old), "before")
+	assert.NotContains(t, string(old), "after")
+
+	current, _ := ioutil.ReadFile(name)
+	assert.Contains(t, string(current), "INFO: ")
+	assert.Contains(t, string(current), "WARNING: ")
+	assert.NotContains(t, string(current), "other")
+	assert.Contains(t, other.String(), "other")
+
+	// all logTypes share the one new file
+	assert.True(t, sameWriter(l.out[INFO], l.out[FATAL]))
+
+	// the file passed in stays open for its owner
+	_, err = f.WriteString("owner\n")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	// a file opened by Reopen is closed by the next one
+	reopened := l.out[INFO].(*os.File)
+	assert.NoError(t, l.Reopen())
+	_, err = reopened.WriteString("closed\n")
+	assert.Error(t, err)
+	l.out[INFO].(*os.File).Close()
+}
+
+func Test_ReopenAsync(t *testing.T) {
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	a := NewAsyncWriter(f, 16, AsyncBlock)
+	l := New(a, DefaultFormat, NoColor, 0)
+	l.Info("before")
+
+	assert.NoError(t, os.Rename(name, name+".1"))
+	assert.NoError(t, l.Reopen())
+	l.Info("after")
+	assert.NoError(t, a.Close())
+
+	old, _ := ioutil.ReadFile(name + ".1")
+	assert.Contains(t, string(old), "before")
+	assert.NotContains(t, string(old), "after")
+
+	current, _ := ioutil.ReadFile(name)
+	assert.Contains(t, string(current), "after")
+	a.w.(*os.File).Close()
+}
+
+func Test_ReopenUnreachable(t *testing.T) {
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	_, pw := io.Pipe()
+	defer pw.Close()
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+	assert.NoError(t, l.Reopen())
+
+	// the file behind a bufio.Writer cannot be reached
+	l.SetOutput(INFO, bufio.NewWriter(f))
+	assert.Error(t, l.Reopen())
+
+	l.SetOutput(INFO, pw)
+	assert.Error(t, l.Reopen())
+}
+
+func Test_ReopenRotatingFile(t *testing.T) {
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	r := &RotatingFile{Filename: name}
+	defer r.Close()
+
+	l := New(r, DefaultFormat, NoColor, 0)
+	l.Info("before")
+
+	assert.NoError(t, os.Rename(name, name+".1"))
+	assert.NoError(t, l.Reopen())
+	l.Info("after")
+
+	current, _ := ioutil.ReadFile(name)
+	assert.Contains(t, string(current), "after")
+	assert.NotContains(t, string(current), "before")
+}
+
+func Test_ReopenLogDir(t *testing.T) {
+
+	dir := t.TempDir()
+	l := New(ioutil.Discard, DefaultFormat, NoColor, 0)
+	assert.NoError(t, l.LogDir(dir))
+
+	l.Info("before")
+	link := filepath.Join(dir, program+".INFO")
+	first, err := os.Readlink(link)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Rename(filepath.Join(dir, first), filepath.Join(dir, "moved")))
+
+	assert.NoError(t, l.Reopen())
+	l.Info("after")
+
+	current, err := ioutil.ReadFile(link)
+	assert.NoError(t, err)
+	assert.Contains(t, string(current), "after")
+	assert.NotContains(t, string(current), "before")
+}
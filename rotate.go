@@ -0,0 +1,331 @@
+package cloudglog
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type rotatePeriod int
+
+const (
+	// rotatePeriod sets the time boundary a RotatingFile rolls over on
+	RotateNever  rotatePeriod = iota // only rotate on MaxSize
+	RotateHourly                     // rotate at the start of every hour
+	RotateDaily                      // rotate at local midnight
+)
+
+// backupTimeFormat is the timestamp in the name of rolled files
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// currentTime is replaced in tests
+var currentTime = time.Now
+
+// RotatingFile is an io.WriteCloser that writes to Filename and rolls it
+// over to name-timestamp.ext when it would grow beyond MaxSize bytes or
+// when the Every period ends. It is safe for concurrent use and opens
+// Filename on the first Write.
+//
+// Example:
+//
+//	cloudglog.LogFile(&cloudglog.RotatingFile{
+//		Filename:   "/var/log/app.log",
+//		MaxSize:    100 << 20,
+//		Every:      cloudglog.RotateDaily,
+//		MaxBackups: 7,
+//		Compress:   true,
+//	})
+type RotatingFile struct {
+	Filename   string        // file to write to, rolled files are kept in the same directory
+	MaxSize    int64         // in bytes, 0 disables size based rotation
+	Every      rotatePeriod  // time based rotation
+	MaxBackups int           // rolled files to keep, 0 keeps all
+	MaxAge     time.Duration // remove rolled files older than MaxAge, 0 keeps all
+	Compress   bool          // gzip rolled files
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	boundary time.Time // next time based rotation, zero for RotateNever
+
+	millMu sync.Mutex // serializes cleanup and compression
+	mills  sync.WaitGroup
+}
+
+// Write writes p to the current file, rolling it over first if needed.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if (r.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxSize) ||
+		(!r.boundary.IsZero() && !currentTime().Before(r.boundary)) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+
+	return n, err
+}
+
+// Rotate rolls the current file over regardless of size and time.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return r.open()
+	}
+
+	return r.rotate()
+}
+
+// Sync commits the current file to stable storage.
+func (r *RotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	return r.file.Sync()
+}
+
+// Close closes the current file and waits for pending cleanup and
+// compression, a later Write opens the file again.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+
+	r.mills.Wait()
+
+	return err
+}
+
+// open opens or creates Filename for appending, r.mu must be held.
+func (r *RotatingFile) open() error {
+
+	if r.Filename == "" {
+		return errors.New("cloudglog: RotatingFile without Filename")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.Filename), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r.file = f
+	r.size = info.Size()
+	r.boundary = nextBoundary(currentTime(), r.Every)
+
+	return nil
+}
+
+// rotate renames the current file to its backup name and opens a new one,
+// r.mu must be held.
+func (r *RotatingFile) rotate() error {
+
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	if err := os.Rename(r.Filename, r.freeBackupName(currentTime())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.mills.Add(1)
+	go r.mill()
+
+	return nil
+}
+
+// backupName returns the name of the file rolled over at t
+func (r *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := r.nameParts()
+	return filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+}
+
+// freeBackupName returns the backup name for t, moved forward until neither
+// the file nor its compressed version exists
+func (r *RotatingFile) freeBackupName(t time.Time) string {
+	for {
+		name := r.backupName(t)
+		if !fileExists(name) && !fileExists(name+".gz") {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func fileExists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+// nameParts splits Filename into directory, backup name prefix and extension
+func (r *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir, base := filepath.Split(r.Filename)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+type backup struct {
+	path string
+	time time.Time
+}
+
+// backups returns the rolled files of r, newest first
+func (r *RotatingFile) backups() ([]backup, error) {
+
+	dir, prefix, ext := r.nameParts()
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimPrefix(name, prefix)
+		stamp = strings.TrimSuffix(stamp, ".gz")
+		if !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(stamp, ext), time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, name), time: t})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+
+	return backups, nil
+}
+
+// mill removes rolled files beyond MaxBackups and MaxAge and compresses the rest
+func (r *RotatingFile) mill() {
+	defer r.mills.Done()
+
+	r.millMu.Lock()
+	defer r.millMu.Unlock()
+
+	backups, err := r.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cloudglog: listing rolled files: %v\n", err)
+		return
+	}
+
+	cutoff := currentTime().Add(-r.MaxAge)
+
+	for i, b := range backups {
+		if (r.MaxBackups > 0 && i >= r.MaxBackups) || (r.MaxAge > 0 && b.time.Before(cutoff)) {
+			if err := os.Remove(b.path); err != nil {
+				fmt.Fprintf(os.Stderr, "cloudglog: removing rolled file: %v\n", err)
+			}
+			continue
+		}
+
+		if r.Compress && !strings.HasSuffix(b.path, ".gz") {
+			if err := gzipFile(b.path); err != nil {
+				fmt.Fprintf(os.Stderr, "cloudglog: compressing rolled file: %v\n", err)
+			}
+		}
+	}
+}
+
+// gzipFile compresses name to name.gz and removes name
+func gzipFile(name string) error {
+
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	in.Close()
+	return os.Remove(name)
+}
+
+// nextBoundary returns the end of the period p that contains t
+func nextBoundary(t time.Time, p rotatePeriod) time.Time {
+
+	switch p {
+	case RotateHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(time.Hour)
+	case RotateDaily:
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	}
+
+	return time.Time{}
+}
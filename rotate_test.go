@@ -0,0 +1,108 @@
+package cloudglog
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RotatingFileSize(t *testing.T) {
+
+	dir := t.TempDir()
+	r := &RotatingFile{Filename: filepath.Join(dir, "app.log"), MaxSize: 10, MaxBackups: 2}
+
+	for i := 0; i < 5; i++ {
+		_, err := r.Write([]byte("0123456789"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, r.Close())
+
+	backups, err := r.backups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+
+	b, err := ioutil.ReadFile(r.Filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", string(b))
+}
+
+func Test_RotatingFileTime(t *testing.T) {
+
+	now := time.Date(2017, 1, 2, 23, 30, 0, 0, time.Local)
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = time.Now }()
+
+	dir := t.TempDir()
+	r := &RotatingFile{Filename: filepath.Join(dir, "app.log"), Every: RotateDaily, Compress: true}
+
+	r.Write([]byte("day one\n"))
+	now = now.Add(time.Hour)
+	r.Write([]byte("day two\n"))
+	assert.NoError(t, r.Close())
+
+	backup := filepath.Join(dir, "app-2017-01-03T00-30-00.000.log.gz")
+	f, err := os.Open(backup)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Equal(t, "day one\n", string(b))
+}
+
+func Test_RotatingFileConcurrent(t *testing.T) {
+
+	dir := t.TempDir()
+	r := &RotatingFile{Filename: filepath.Join(dir, "app.log"), MaxSize: 1 << 10}
+	l := New(r, DefaultFormat, NoColor, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				l.Info("concurrent")
+				l.Error("concurrent")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.NoError(t, r.Close())
+
+	backups, err := r.backups()
+	assert.NoError(t, err)
+
+	lines := 0
+	for _, name := range append([]string{r.Filename}, backupPaths(backups)...) {
+		b, err := ioutil.ReadFile(name)
+		assert.NoError(t, err)
+		lines += strings.Count(string(b), "concurrent\n")
+	}
+	assert.Equal(t, 400, lines)
+}
+
+func Test_NextBoundary(t *testing.T) {
+
+	ts := time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC)
+	assert.Equal(t, time.Date(2017, 1, 2, 16, 0, 0, 0, time.UTC), nextBoundary(ts, RotateHourly))
+	assert.Equal(t, time.Date(2017, 1, 3, 0, 0, 0, 0, time.UTC), nextBoundary(ts, RotateDaily))
+	assert.True(t, nextBoundary(ts, RotateNever).IsZero())
+}
+
+func backupPaths(backups []backup) []string {
+	paths := make([]string, len(backups))
+	for i, b := range backups {
+		paths[i] = b.path
+	}
+	return paths
+}
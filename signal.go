@@ -0,0 +1,11 @@
+package cloudglog
+
+// HandleSignals makes the default Logger react to SIGUSR1, SIGUSR2 and
+// SIGHUP until the returned stop function is called, see Logger.HandleSignals.
+//
+// Example:
+//
+//	defer cloudglog.HandleSignals()()
+func HandleSignals() (stop func()) {
+	return std.HandleSignals()
+}
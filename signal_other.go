@@ -0,0 +1,9 @@
+//go:build !unix
+
+package cloudglog
+
+// HandleSignals does nothing on systems without SIGUSR1 and SIGUSR2, like
+// Windows, call Reopen directly instead.
+func (l *Logger) HandleSignals() (stop func()) {
+	return func() {}
+}
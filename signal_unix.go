@@ -0,0 +1,72 @@
+//go:build unix
+
+package cloudglog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleSignals makes l react to signals until the returned stop function
+// is called:
+//
+//	SIGUSR1	raise the V() level by one
+//	SIGUSR2	restore the V() level l had when HandleSignals was called
+//	SIGHUP	reopen the file outputs, see Reopen for the outputs it cannot reopen
+//
+// Each change is logged to the INFO log. Signal handling is only available
+// on Unix systems, HandleSignals does nothing elsewhere.
+func (l *Logger) HandleSignals() (stop func()) {
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGHUP)
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	base := l.GetLogLevel()
+
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case s := <-c:
+				l.handleSignal(s, base)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+			<-exited
+		})
+	}
+}
+
+// handleSignal acts on s, base is the level SIGUSR2 restores
+func (l *Logger) handleSignal(s os.Signal, base int) {
+
+	switch s {
+	case syscall.SIGUSR1:
+		level := l.GetLogLevel() + 1
+		l.SetLogLevel(level)
+		l.Infof("cloudglog: V level raised to %d on %v", level, s)
+
+	case syscall.SIGUSR2:
+		l.SetLogLevel(base)
+		l.Infof("cloudglog: V level restored to %d on %v", base, s)
+
+	case syscall.SIGHUP:
+		if err := l.Reopen(); err != nil {
+			l.Errorf("cloudglog: reopening log files on %v: %v", s, err)
+			return
+		}
+		l.Infof("cloudglog: log files reopened on %v", s)
+	}
+}
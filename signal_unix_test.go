@@ -0,0 +1,39 @@
+//go:build unix
+
+package cloudglog
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_HandleSignals(t *testing.T) {
+
+	var b syncBuffer
+	l := New(&b, DefaultFormat, NoColor, 1)
+	stop := l.HandleSignals()
+	defer stop()
+
+	level := func(want int) func() bool {
+		return func() bool { return l.GetLogLevel() == want }
+	}
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, level(2), time.Second, time.Millisecond)
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, level(3), time.Second, time.Millisecond)
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	assert.Eventually(t, level(1), time.Second, time.Millisecond)
+
+	assert.Contains(t, b.String(), "V level raised to 3 on user defined signal 1")
+	assert.Contains(t, b.String(), "V level restored to 1")
+
+	// stop is idempotent and ends the handling
+	stop()
+	stop()
+}
@@ -0,0 +1,245 @@
+package cloudglog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// SlogHandler is a slog.Handler that writes slog records with the format,
+// color style and outputs of a Logger. Attributes become fields, the keys
+// of attributes in groups are qualified with the group names separated by
+// dots. slog.LevelDebug and below are logged to the TRACE log.
+//
+// Example:
+//
+//	l := cloudglog.New(os.Stdout, cloudglog.ModernFormat, cloudglog.FullColor, 0)
+//	slog.SetDefault(slog.New(cloudglog.NewSlogHandler(l, nil)))
+type SlogHandler struct {
+	l      *Logger
+	opts   slog.HandlerOptions
+	fields []Field  // attributes of WithAttrs, their keys are qualified
+	groups []string // groups of WithGroup
+	prefix string   // the groups joined by dots, with a trailing dot
+}
+
+// NewSlogHandler returns a SlogHandler that logs to l. Of opts, Level sets
+// the minimum level in addition to the settings of l and ReplaceAttr is
+// called for the attributes, the time, level, message and source are
+// written by the format and not passed to it. opts may be nil.
+func NewSlogHandler(l *Logger, opts *slog.HandlerOptions) *SlogHandler {
+	h := &SlogHandler{l: l}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// slogLogType maps a slog level to the logType it is logged to
+func slogLogType(level slog.Level) logType {
+	switch {
+	case level >= slog.LevelError:
+		return ERROR
+	case level >= slog.LevelWarn:
+		return WARNING
+	case level >= slog.LevelInfo:
+		return INFO
+	}
+	return TRACE
+}
+
+// slogLevels map the logTypes to slog levels
+var slogLevels = []slog.Level{
+	TRACE:   slog.LevelDebug,
+	INFO:    slog.LevelInfo,
+	WARNING: slog.LevelWarn,
+	ERROR:   slog.LevelError,
+	FATAL:   slog.LevelError + 4,
+}
+
+// Enabled reports whether records at level are logged.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+
+	if h.opts.Level != nil && level < h.opts.Level.Level() {
+		return false
+	}
+
+	return slogLogType(level) != TRACE || h.l.TraceEnabled()
+}
+
+// Handle writes r with the fields of RegisterContextFields taken from ctx.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+
+	rec := getRecord()
+	defer putRecord(rec)
+
+	rec.Time = r.Time
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	rec.Level = slogLogType(r.Level)
+	rec.Message = r.Message
+	rec.pc = r.PC
+
+	fields := append(h.l.fields[:len(h.l.fields):len(h.l.fields)], h.fields...)
+	fields = contextFields(ctx, fields)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = h.appendAttr(fields, h.groups, h.prefix, a)
+		return true
+	})
+	rec.Fields = fields
+
+	h.l.write(rec)
+
+	return nil
+}
+
+// WithAttrs returns a SlogHandler that adds attrs to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+
+	if len(attrs) == 0 {
+		return h
+	}
+
+	h2 := *h
+	h2.fields = h.fields[:len(h.fields):len(h.fields)]
+	for _, a := range attrs {
+		h2.fields = h.appendAttr(h2.fields, h.groups, h.prefix, a)
+	}
+
+	return &h2
+}
+
+// WithGroup returns a SlogHandler that puts the attributes that follow in
+// the group name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	h2.prefix = h.prefix + name + "."
+
+	return &h2
+}
+
+// appendAttr appends a to fields, groups are flattened into keys qualified
+// with prefix, empty attributes and groups are left out.
+func (h *SlogHandler) appendAttr(fields []Field, groups []string, prefix string, a slog.Attr) []Field {
+
+	a.Value = a.Value.Resolve()
+	if h.opts.ReplaceAttr != nil && a.Value.Kind() != slog.KindGroup {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		// a group without a key is inlined
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+			prefix += a.Key + "."
+		}
+		for _, ga := range attrs {
+			fields = h.appendAttr(fields, groups, prefix, ga)
+		}
+		return fields
+	}
+
+	return append(fields, Field{Key: prefix + a.Key, Value: slogValue(a.Value)})
+}
+
+// slogValue returns the Go value of a resolved slog.Value
+func slogValue(v slog.Value) interface{} {
+
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration()
+	case slog.KindTime:
+		return v.Time()
+	}
+
+	return v.Any()
+}
+
+// SetSlogHandler routes the records of l through h instead of writing
+// them with the format and outputs of l, nil turns routing off. Fields
+// become attributes and the goroutine stacks of Fatal the "stack"
+// attribute, the TRACE log maps to slog.LevelDebug and FATAL to
+// slog.LevelError+4. Calls h.Enabled rejects are dropped, Fatal and
+// Exit exit regardless.
+func (l *Logger) SetSlogHandler(h slog.Handler) {
+	l.slog.Store(slogRoute{h})
+}
+
+// SetSlogHandler routes the package level log functions through h,
+// see Logger.SetSlogHandler.
+//
+// Example:
+//
+//	cloudglog.SetSlogHandler(slog.NewJSONHandler(os.Stderr, nil))
+func SetSlogHandler(h slog.Handler) {
+	std.SetSlogHandler(h)
+}
+
+// slogRoute holds the handler of SetSlogHandler, atomic.Value cannot
+// store a nil interface
+type slogRoute struct {
+	h slog.Handler
+}
+
+// slogHandler returns the handler set with SetSlogHandler or nil
+func (l *Logger) slogHandler() slog.Handler {
+	r, _ := l.slog.Load().(slogRoute)
+	return r.h
+}
+
+// writeSlog hands a record to h, tm is the time and pc the program counter
+// of the log call. src is the caller of a record without pc, it is added
+// as the slog.SourceKey attribute, nil if unknown.
+func (l *Logger) writeSlog(h slog.Handler, t logType, tm time.Time, pc uintptr, src *slog.Source, s string, stack []byte, fields []Field) {
+
+	ctx := context.Background()
+	level := slogLevels[t]
+	if !h.Enabled(ctx, level) {
+		return
+	}
+
+	// s may be a pooled buffer, the handler may keep the record
+	r := slog.NewRecord(tm, level, strings.Clone(strings.TrimSuffix(s, "\n")), pc)
+
+	for _, f := range l.fields {
+		r.AddAttrs(slog.Any(f.Key, f.Value))
+	}
+	for _, f := range fields {
+		r.AddAttrs(slog.Any(f.Key, f.Value))
+	}
+	if src != nil {
+		r.AddAttrs(slog.Any(slog.SourceKey, src))
+	}
+	if len(stack) > 0 {
+		r.AddAttrs(slog.String("stack", string(stack)))
+	}
+
+	h.Handle(ctx, r)
+}
@@ -0,0 +1,98 @@
+package cloudglog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type secret string
+
+func (secret) LogValue() slog.Value {
+	return slog.StringValue("***")
+}
+
+func Test_SlogHandler(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, ModernFormat, NoColor, 0)
+	log := slog.New(NewSlogHandler(l, nil))
+
+	log.Info("hello", "k", 1, slog.Group("g", "a", "b"), slog.Group("empty"), "pw", secret("x"))
+	assert.True(t, strings.HasPrefix(b.String(), "INFO: "), "unexpected output %q", b.String())
+	assert.Contains(t, b.String(), "][slog_test.go][:")
+	assert.True(t, strings.HasSuffix(b.String(), "]\t hello k=1 g.a=b pw=***\n"), "unexpected output %q", b.String())
+
+	b.Reset()
+	log.With("x", 1).WithGroup("req").With("id", 7).Warn("done", "ms", 12, slog.Group("", "inline", true))
+	assert.True(t, strings.HasPrefix(b.String(), "WARNING: "), "unexpected output %q", b.String())
+	assert.True(t, strings.HasSuffix(b.String(), " done x=1 req.id=7 req.ms=12 req.inline=true\n"), "unexpected output %q", b.String())
+
+	// debug goes to the TRACE log
+	b.Reset()
+	log.Debug("hidden")
+	assert.Equal(t, 0, b.Len())
+	l.EnableTrace(true)
+	log.Debug("shown")
+	assert.True(t, strings.HasPrefix(b.String(), "TRACE: "), "unexpected output %q", b.String())
+
+	// fields of the Logger come first
+	b.Reset()
+	slog.New(NewSlogHandler(l.With("svc", "api"), nil)).Error("failed", "err", "boom")
+	assert.True(t, strings.HasSuffix(b.String(), " failed svc=api err=boom\n"), "unexpected output %q", b.String())
+}
+
+func Test_SlogHandlerOptions(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, JSONFormat, NoColor, 0)
+	log := slog.New(NewSlogHandler(l, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "drop" {
+				return slog.Attr{}
+			}
+			if len(groups) > 0 {
+				a.Key = strings.ToUpper(a.Key)
+			}
+			return a
+		},
+	}))
+
+	log.Info("hidden")
+	assert.Equal(t, 0, b.Len())
+
+	log.Warn("shown", "drop", 1, slog.Group("g", "k", "v"))
+	assert.Contains(t, b.String(), `"severity":"WARNING","file":"slog_test.go",`)
+	assert.True(t, strings.HasSuffix(b.String(), `"message":"shown","g.K":"v"}`+"\n"), "unexpected output %q", b.String())
+}
+
+func Test_SetSlogHandler(t *testing.T) {
+
+	var out, routed bytes.Buffer
+	l := New(&out, DefaultFormat, NoColor, 0)
+	l.SetSlogHandler(slog.NewTextHandler(&routed, &slog.HandlerOptions{AddSource: true}))
+
+	l.With("svc", "api").Infow("hello", "k", 1)
+	l.Warningf("count %d", 2)
+	l.Trace("off")
+	assert.Equal(t, 0, out.Len())
+	assert.Contains(t, routed.String(), `level=INFO source=`)
+	assert.Contains(t, routed.String(), `slog_test.go:`)
+	assert.Contains(t, routed.String(), ` msg=hello svc=api k=1`)
+	assert.Contains(t, routed.String(), `level=WARN `)
+	assert.Contains(t, routed.String(), ` msg="count 2"`)
+	assert.NotContains(t, routed.String(), "off")
+
+	routed.Reset()
+	l.fatalOutput(CallDepth, "fatal")
+	assert.Contains(t, routed.String(), `level=ERROR+4 `)
+	assert.Contains(t, routed.String(), ` stack="goroutine `)
+
+	l.SetSlogHandler(nil)
+	l.Info("direct")
+	assert.Contains(t, out.String(), ": direct\n")
+}
@@ -0,0 +1,153 @@
+package cloudglog
+
+import (
+	"io"
+	"log"
+	"strings"
+)
+
+// SeverityRule sends the captured lines whose message starts with Prefix
+// to the Level log, the prefix is compared case-insensitively and removed
+// from the message.
+type SeverityRule struct {
+	Prefix string
+	Level  logType
+}
+
+// DefaultSeverityRules are the rules used when nil rules are given, they
+// cover the common "ERROR:" and "[warn]" styles of prefixes.
+var DefaultSeverityRules = []SeverityRule{
+	{"[fatal]", FATAL}, {"fatal:", FATAL},
+	{"[panic]", FATAL}, {"panic:", FATAL},
+	{"[error]", ERROR}, {"error:", ERROR},
+	{"[err]", ERROR}, {"err:", ERROR},
+	{"[warning]", WARNING}, {"warning:", WARNING},
+	{"[warn]", WARNING}, {"warn:", WARNING},
+	{"[info]", INFO}, {"info:", INFO},
+	{"[debug]", TRACE}, {"debug:", TRACE},
+	{"[trace]", TRACE}, {"trace:", TRACE},
+}
+
+// stdLogWriter turns the lines of a log.Logger into records of a Logger,
+// see Logger.StdLogWriter.
+type stdLogWriter struct {
+	l       *Logger
+	logType logType
+	rules   []SeverityRule
+	logger  *log.Logger // writing to w, nil if unknown
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+
+	// with the prefix and flags of the log.Logger its header is taken apart
+	// exactly, otherwise it is guessed
+	prefix, flags := "", -1
+	if w.logger != nil {
+		prefix, flags = w.logger.Prefix(), w.logger.Flags()
+	}
+
+	r := parseLine(string(p), w.logType, prefix, flags)
+	if t, msg, ok := matchSeverity(r.Message, w.rules); ok {
+		r.Level, r.Message = t, msg
+	}
+
+	if r.Level != TRACE || w.l.TraceEnabled() {
+		w.l.LogRecord(r)
+	}
+
+	return len(p), nil
+}
+
+// matchSeverity returns the log of the first rule whose prefix msg starts
+// with and msg without the prefix
+func matchSeverity(msg string, rules []SeverityRule) (logType, string, bool) {
+
+	s := strings.TrimLeft(msg, " \t")
+	for _, rule := range rules {
+		n := len(rule.Prefix)
+		if n == 0 || len(s) < n || !strings.EqualFold(s[:n], rule.Prefix) {
+			continue
+		}
+		return rule.Level, strings.TrimLeft(s[n:], " \t"), true
+	}
+
+	return 0, msg, false
+}
+
+// StdLogWriter returns an io.Writer for log.New or log.SetOutput that
+// rewrites the lines of a log.Logger as records of l. Lines that no rule
+// matches go to the t log. nil rules are DefaultSeverityRules, an empty
+// slice turns the matching off. Lines of the TRACE log are dropped while
+// it is disabled.
+//
+// The writer does not know the prefix and flags of the log.Logger, it only
+// keeps the date, time and file:line of lines that start with them, after
+// at most a prefix word, and that have a file:line: word. Use StdLogger to
+// keep them for any flags.
+//
+// Unlike LogFilter it re-levels the lines and writes them with the format,
+// outputs and fields of l.
+func (l *Logger) StdLogWriter(t logType, rules []SeverityRule) io.Writer {
+	return l.stdLogWriter(t, rules, nil)
+}
+
+// stdLogWriter returns the writer of StdLogWriter for the log.Logger lg
+func (l *Logger) stdLogWriter(t logType, rules []SeverityRule, lg *log.Logger) *stdLogWriter {
+	if rules == nil {
+		rules = DefaultSeverityRules
+	}
+	return &stdLogWriter{l: l, logType: t, rules: rules, logger: lg}
+}
+
+// StdLogger returns a log.Logger with prefix and flags that writes its
+// lines as records of l, see StdLogWriter. The date, time and file:line it
+// writes are kept in the records.
+func (l *Logger) StdLogger(t logType, prefix string, flags int, rules []SeverityRule) *log.Logger {
+	lg := log.New(io.Discard, prefix, flags)
+	lg.SetOutput(l.stdLogWriter(t, rules, lg))
+	return lg
+}
+
+// CaptureStdLog redirects the standard library's default logger to l, see
+// StdLogWriter. Lines that no rule matches go to the INFO log. The flags
+// of the log package are set to write the date, time and file:line that
+// are kept in the records, restore sets the output and flags back.
+func (l *Logger) CaptureStdLog(rules []SeverityRule) (restore func()) {
+
+	out, flags := log.Writer(), log.Flags()
+
+	log.SetOutput(l.stdLogWriter(INFO, rules, log.Default()))
+	log.SetFlags(flags&log.Lmsgprefix | log.Ldate | log.Ltime | log.Lmicroseconds | log.Llongfile)
+
+	return func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+	}
+}
+
+// StdLogWriter returns an io.Writer that rewrites the lines of a
+// log.Logger as records of the default Logger, see Logger.StdLogWriter.
+func StdLogWriter(t logType, rules []SeverityRule) io.Writer {
+	return std.StdLogWriter(t, rules)
+}
+
+// StdLogger returns a log.Logger that writes its lines as records of the
+// default Logger, see Logger.StdLogger.
+//
+// Example:
+//
+//	srv.ErrorLog = cloudglog.StdLogger(cloudglog.ERROR, "", log.Lshortfile, nil)
+func StdLogger(t logType, prefix string, flags int, rules []SeverityRule) *log.Logger {
+	return std.StdLogger(t, prefix, flags, rules)
+}
+
+// CaptureStdLog redirects the standard library's default logger to the
+// default Logger, see Logger.CaptureStdLog.
+//
+// Example:
+//
+//	defer cloudglog.CaptureStdLog(nil)()
+//	log.Print("[warn] disk almost full") // WARNING: ... disk almost full
+func CaptureStdLog(rules []SeverityRule) (restore func()) {
+	return std.CaptureStdLog(rules)
+}
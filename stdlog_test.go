@@ -0,0 +1,108 @@
+package cloudglog
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StdLogWriter(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+	lg := log.New(l.StdLogWriter(INFO, nil), "", log.Ldate|log.Ltime|log.Lshortfile)
+
+	tests := []struct {
+		msg, prefix, suffix string
+	}{
+		{"started", "INFO: ", " stdlog_test.go:31: started\n"},
+		{"ERROR: connection refused", "ERROR: ", " stdlog_test.go:31: connection refused\n"},
+		{"[warn] disk almost full", "WARNING: ", " stdlog_test.go:31: disk almost full\n"},
+		{"  Warning: retrying", "WARNING: ", " stdlog_test.go:31: retrying\n"},
+		{"[Fatal] gone", "Fatal: ", " stdlog_test.go:31: gone\n"},
+		{"errors are values", "INFO: ", " stdlog_test.go:31: errors are values\n"},
+	}
+
+	for _, test := range tests {
+		b.Reset()
+		lg.Print(test.msg)
+		assert.True(t, strings.HasPrefix(b.String(), test.prefix), "unexpected output %q", b.String())
+		assert.True(t, strings.HasSuffix(b.String(), test.suffix), "unexpected output %q", b.String())
+	}
+
+	// TRACE lines only while the TRACE log is enabled
+	b.Reset()
+	lg.Print("debug: hidden")
+	assert.Empty(t, b.String())
+
+	l.EnableTrace(true)
+	lg.Print("debug: shown")
+	assert.True(t, strings.HasPrefix(b.String(), "TRACE: "), "unexpected output %q", b.String())
+}
+
+func Test_StdLogWriterRules(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+
+	// the prefix of the log.Logger before the date
+	lg := l.StdLogger(WARNING, "E! ", log.LstdFlags, []SeverityRule{{"E!", ERROR}})
+	lg.Print("broken")
+	assert.True(t, strings.HasPrefix(b.String(), "ERROR: "), "unexpected output %q", b.String())
+	assert.True(t, strings.HasSuffix(b.String(), " ???:0: broken\n"), "unexpected output %q", b.String())
+
+	// the default rules do not apply
+	b.Reset()
+	lg = log.New(l.StdLogWriter(WARNING, []SeverityRule{}), "", 0)
+	lg.Print("error: kept")
+	assert.True(t, strings.HasPrefix(b.String(), "WARNING: "), "unexpected output %q", b.String())
+	assert.True(t, strings.HasSuffix(b.String(), ": error: kept\n"), "unexpected output %q", b.String())
+}
+
+func Test_StdLogger(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+
+	// a logger without flags, the time belongs to the message
+	lg := l.StdLogger(INFO, "", 0, nil)
+	lg.Print("retry at 10:00:00 failed")
+	assert.True(t, strings.HasSuffix(b.String(), " ???:0: retry at 10:00:00 failed\n"), "unexpected output %q", b.String())
+
+	b.Reset()
+	l.StdLogWriter(INFO, nil).Write([]byte("retry at 10:00:00 failed\n"))
+	assert.True(t, strings.HasSuffix(b.String(), " ???:0: retry at 10:00:00 failed\n"), "unexpected output %q", b.String())
+
+	// the prefix and flags set later are followed
+	b.Reset()
+	lg.SetPrefix("[db] ")
+	lg.SetFlags(log.Ltime | log.Lshortfile | log.Lmsgprefix)
+	lg.Print("warn: 10:00:00 slow")
+	assert.True(t, strings.HasPrefix(b.String(), "INFO: "), "unexpected output %q", b.String())
+	assert.True(t, strings.HasSuffix(b.String(), " stdlog_test.go:83: [db] warn: 10:00:00 slow\n"), "unexpected output %q", b.String())
+
+	b.Reset()
+	lg.SetPrefix("")
+	lg.Print("warn: 10:00:00 slow")
+	assert.True(t, strings.HasPrefix(b.String(), "WARNING: "), "unexpected output %q", b.String())
+	assert.True(t, strings.HasSuffix(b.String(), " stdlog_test.go:89: 10:00:00 slow\n"), "unexpected output %q", b.String())
+}
+
+func Test_CaptureStdLog(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, ModernFormat, NoColor, 0).With("source", "stdlog")
+
+	out, flags := log.Writer(), log.Flags()
+	restore := l.CaptureStdLog(nil)
+	log.Printf("[error] %d failures", 3)
+	restore()
+
+	assert.True(t, strings.HasPrefix(b.String(), "ERROR: "), "unexpected output %q", b.String())
+	assert.True(t, strings.HasSuffix(b.String(), "[stdlog_test.go][:101]\t 3 failures source=stdlog\n"), "unexpected output %q", b.String())
+	assert.Equal(t, flags, log.Flags())
+	assert.Equal(t, out, log.Writer())
+}
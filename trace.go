@@ -0,0 +1,171 @@
+package cloudglog
+
+import (
+	"sync/atomic"
+)
+
+// EnableTrace turns the TRACE log on or off.
+func (l *Logger) EnableTrace(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&l.trace, v)
+}
+
+// TraceEnabled reports whether the TRACE log is on.
+func (l *Logger) TraceEnabled() bool {
+	return atomic.LoadInt32(&l.trace) == 1
+}
+
+// Trace logs to the TRACE log.
+// Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
+func (l *Logger) Trace(args ...interface{}) {
+	if l.TraceEnabled() {
+		l.outputPrint(TRACE, CallDepth, args)
+	}
+}
+
+// TraceDepth acts as Trace but uses depth to determine which call frame to log.
+// TraceDepth(0, "msg") is the same as Trace("msg").
+func (l *Logger) TraceDepth(depth int, args ...interface{}) {
+	if l.TraceEnabled() {
+		l.outputPrint(TRACE, CallDepth+depth, args)
+	}
+}
+
+// Traceln logs to the TRACE log.
+// Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
+func (l *Logger) Traceln(args ...interface{}) {
+	if l.TraceEnabled() {
+		l.outputPrintln(TRACE, CallDepth, args)
+	}
+}
+
+// Tracef logs to the TRACE log.
+// Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
+func (l *Logger) Tracef(format string, args ...interface{}) {
+	if l.TraceEnabled() {
+		l.outputPrintf(TRACE, CallDepth, format, args)
+	}
+}
+
+// Tracew logs msg and the key/value pairs in kv to the TRACE log.
+func (l *Logger) Tracew(msg string, kv ...interface{}) {
+	if l.TraceEnabled() {
+		l.output(TRACE, CallDepth, msg, makeFields(kv)...)
+	}
+}
+
+// Trace is equivalent to Logger.Trace, guarded by the value of v.
+func (v Verbose) Trace(args ...interface{}) {
+	if v.enabled && v.l.TraceEnabled() {
+		v.l.outputPrint(TRACE, CallDepth, args)
+	}
+}
+
+// TraceDepth is equivalent to Logger.TraceDepth, guarded by the value of v.
+func (v Verbose) TraceDepth(depth int, args ...interface{}) {
+	if v.enabled && v.l.TraceEnabled() {
+		v.l.outputPrint(TRACE, CallDepth+depth, args)
+	}
+}
+
+// Traceln is equivalent to Logger.Traceln, guarded by the value of v.
+func (v Verbose) Traceln(args ...interface{}) {
+	if v.enabled && v.l.TraceEnabled() {
+		v.l.outputPrintln(TRACE, CallDepth, args)
+	}
+}
+
+// Tracef is equivalent to Logger.Tracef, guarded by the value of v.
+func (v Verbose) Tracef(format string, args ...interface{}) {
+	if v.enabled && v.l.TraceEnabled() {
+		v.l.outputPrintf(TRACE, CallDepth, format, args)
+	}
+}
+
+// EnableTrace turns the TRACE log on or off, it can also be turned on
+// with LOG_LEVEL=trace.
+func EnableTrace(enabled bool) {
+	std.EnableTrace(enabled)
+}
+
+// TraceEnabled reports whether the TRACE log is on. Use it to guard
+// expensive arguments:
+//
+//	if cloudglog.TraceEnabled() { cloudglog.Trace(dump()) }
+func TraceEnabled() bool {
+	return std.TraceEnabled()
+}
+
+// Trace logs to the TRACE log.
+// Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
+func Trace(args ...interface{}) {
+	if std.TraceEnabled() {
+		std.outputPrint(TRACE, CallDepth, args)
+	}
+}
+
+// TraceDepth acts as Trace but uses depth to determine which call frame to log.
+// TraceDepth(0, "msg") is the same as Trace("msg").
+func TraceDepth(depth int, args ...interface{}) {
+	if std.TraceEnabled() {
+		std.outputPrint(TRACE, CallDepth+depth, args)
+	}
+}
+
+// Traceln logs to the TRACE log.
+// Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
+func Traceln(args ...interface{}) {
+	if std.TraceEnabled() {
+		std.outputPrintln(TRACE, CallDepth, args)
+	}
+}
+
+// Tracef logs to the TRACE log.
+// Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
+func Tracef(format string, args ...interface{}) {
+	if std.TraceEnabled() {
+		std.outputPrintf(TRACE, CallDepth, format, args)
+	}
+}
+
+// Tracew logs msg and the key/value pairs in kv to the TRACE log.
+func Tracew(msg string, kv ...interface{}) {
+	if std.TraceEnabled() {
+		std.output(TRACE, CallDepth, msg, makeFields(kv)...)
+	}
+}
+
+// Trace is equivalent to the global Trace function, guarded by the value of v.
+// See the documentation of V for usage.
+func (v Verbosity) Trace(args ...interface{}) {
+	if bool(v) && std.TraceEnabled() {
+		std.outputPrint(TRACE, CallDepth, args)
+	}
+}
+
+// TraceDepth is equivalent to the global TraceDepth function, guarded by the value of v.
+// See the documentation of V for usage.
+func (v Verbosity) TraceDepth(depth int, args ...interface{}) {
+	if bool(v) && std.TraceEnabled() {
+		std.outputPrint(TRACE, CallDepth+depth, args)
+	}
+}
+
+// Traceln is equivalent to the global Traceln function, guarded by the value of v.
+// See the documentation of V for usage.
+func (v Verbosity) Traceln(args ...interface{}) {
+	if bool(v) && std.TraceEnabled() {
+		std.outputPrintln(TRACE, CallDepth, args)
+	}
+}
+
+// Tracef is equivalent to the global Tracef function, guarded by the value of v.
+// See the documentation of V for usage.
+func (v Verbosity) Tracef(format string, args ...interface{}) {
+	if bool(v) && std.TraceEnabled() {
+		std.outputPrintf(TRACE, CallDepth, format, args)
+	}
+}
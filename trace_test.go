@@ -0,0 +1,47 @@
+package cloudglog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingStringer struct {
+	calls int
+}
+
+func (c *countingStringer) String() string {
+	c.calls++
+	return "expensive"
+}
+
+func Test_Trace(t *testing.T) {
+
+	var b bytes.Buffer
+	l := New(&b, DefaultFormat, NoColor, 0)
+
+	// disabled trace does not format its arguments
+	arg := &countingStringer{}
+	l.Trace(arg)
+	l.Tracef("%s", arg)
+	assert.Equal(t, 0, b.Len())
+	assert.Equal(t, 0, arg.calls)
+
+	l.EnableTrace(true)
+	assert.True(t, l.TraceEnabled())
+
+	l.Tracef("%s", arg)
+	assert.True(t, strings.HasPrefix(b.String(), "TRACE: "), "unexpected output %q", b.String())
+	assert.True(t, strings.HasSuffix(b.String(), ": expensive\n"), "unexpected output %q", b.String())
+	assert.Equal(t, 1, arg.calls)
+
+	b.Reset()
+	l.V(1).Trace("hidden")
+	assert.Equal(t, 0, b.Len())
+
+	l.EnableTrace(false)
+	l.V(0).Trace("hidden")
+	assert.Equal(t, 0, b.Len())
+}
@@ -0,0 +1,173 @@
+package cloudglog
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// modulePat is one pattern=level entry of a vmodule spec
+type modulePat struct {
+	pattern string
+	literal bool // the pattern has no glob characters, compare with ==
+	parts   int  // path elements the pattern matches against
+	level   int
+}
+
+// match reports whether file, without its .go suffix, matches p
+func (p *modulePat) match(file string) bool {
+
+	// keep the last p.parts elements of file
+	i := len(file)
+	for n := 0; n < p.parts && i >= 0; n++ {
+		i = strings.LastIndexByte(file[:i], '/')
+	}
+	file = file[i+1:]
+
+	if p.literal {
+		return file == p.pattern
+	}
+
+	ok, _ := path.Match(p.pattern, file)
+	return ok
+}
+
+// vmodule is a parsed vmodule spec, the level of each call site of V is
+// resolved once and cached by its program counter.
+type vmodule struct {
+	spec     string
+	patterns []modulePat
+	sites    sync.Map // uintptr -> int, -1 if no pattern matches
+}
+
+// parseVModule parses a comma separated list of pattern=level entries
+func parseVModule(spec string) (*vmodule, error) {
+
+	vm := &vmodule{spec: spec}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		i := strings.LastIndexByte(entry, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("cloudglog: vmodule entry %q is not pattern=level", entry)
+		}
+
+		pattern := strings.TrimSuffix(entry[:i], ".go")
+		if pattern == "" {
+			return nil, fmt.Errorf("cloudglog: vmodule entry %q has no pattern", entry)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("cloudglog: vmodule pattern %q: %v", pattern, err)
+		}
+
+		level, err := strconv.Atoi(entry[i+1:])
+		if err != nil || level < 0 {
+			return nil, fmt.Errorf("cloudglog: vmodule entry %q has no valid level", entry)
+		}
+
+		vm.patterns = append(vm.patterns, modulePat{
+			pattern: pattern,
+			literal: !strings.ContainsAny(pattern, `*?[\`),
+			parts:   strings.Count(pattern, "/") + 1,
+			level:   level,
+		})
+	}
+
+	if len(vm.patterns) == 0 {
+		return nil, errors.New("cloudglog: empty vmodule")
+	}
+
+	return vm, nil
+}
+
+// level returns the level of the call site pc, -1 if no pattern matches it
+func (vm *vmodule) level(pc uintptr) int {
+
+	if v, ok := vm.sites.Load(pc); ok {
+		return v.(int)
+	}
+
+	level := -1
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	file := strings.TrimSuffix(frame.File, ".go")
+	for i := range vm.patterns {
+		if vm.patterns[i].match(file) {
+			level = vm.patterns[i].level
+			break
+		}
+	}
+
+	vm.sites.Store(pc, level)
+	return level
+}
+
+// SetVModule sets per file verbosity levels in the manner of glog's
+// -vmodule, spec is a comma separated list of pattern=level entries.
+// A pattern without a slash matches the base name of the source file, one
+// with slashes matches the same number of trailing path elements, the
+// .go suffix is ignored and the first matching entry wins:
+//
+//	server=3,db/*=2
+//
+// sets V level 3 in server.go and 2 in all files of the db package. The
+// level of a call site only ever raises the level set with SetLogLevel,
+// an empty spec removes all entries.
+func (l *Logger) SetVModule(spec string) error {
+
+	if strings.TrimSpace(spec) == "" {
+		l.vmodule.Store((*vmodule)(nil))
+		return nil
+	}
+
+	vm, err := parseVModule(spec)
+	if err != nil {
+		return err
+	}
+
+	l.vmodule.Store(vm)
+	return nil
+}
+
+// VModule returns the spec set with SetVModule.
+func (l *Logger) VModule() string {
+	if vm, _ := l.vmodule.Load().(*vmodule); vm != nil {
+		return vm.spec
+	}
+	return ""
+}
+
+// vmoduleLevel returns the vmodule level of the caller of the function
+// calling vmoduleLevel, depth frames further up, -1 if there is none.
+func (l *Logger) vmoduleLevel(depth int) int {
+
+	vm, _ := l.vmodule.Load().(*vmodule)
+	if vm == nil {
+		return -1
+	}
+
+	var pcs [1]uintptr
+	if runtime.Callers(3+depth, pcs[:]) == 0 {
+		return -1
+	}
+
+	return vm.level(pcs[0])
+}
+
+// SetVModule sets per file verbosity levels of the default Logger,
+// see Logger.SetVModule. It can also be set by the LOG_VMODULE
+// environment variable.
+//
+// Example:
+//
+//	cloudglog.SetVModule("server=3,db/*=2")
+func SetVModule(spec string) error {
+	return std.SetVModule(spec)
+}
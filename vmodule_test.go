@@ -0,0 +1,88 @@
+package cloudglog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseVModule(t *testing.T) {
+
+	vm, err := parseVModule("server=3, db/*.go=2")
+	assert.NoError(t, err)
+	assert.Equal(t, []modulePat{
+		{pattern: "server", literal: true, parts: 1, level: 3},
+		{pattern: "db/*", literal: false, parts: 2, level: 2},
+	}, vm.patterns)
+
+	for _, spec := range []string{"server", "=1", "server=x", "server=-1", "[=1", ","} {
+		_, err := parseVModule(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func Test_modulePatMatch(t *testing.T) {
+
+	tests := []struct {
+		spec string
+		file string
+		want bool
+	}{
+		{"server=1", "/src/app/server", true},
+		{"server=1", "/src/app/server_test", false},
+		{"serv*=1", "/src/app/server_test", true},
+		{"db/*=1", "/src/app/db/query", true},
+		{"db/*=1", "/src/app/dbx/query", false},
+		{"app/db/query=1", "/src/app/db/query", true},
+		{"src/app/db/query=1", "app/db/query", false},
+	}
+
+	for _, tt := range tests {
+		vm, err := parseVModule(tt.spec)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, vm.patterns[0].match(tt.file), "%s %s", tt.spec, tt.file)
+	}
+}
+
+func Test_LoggerVModule(t *testing.T) {
+
+	l := New(nil, DefaultFormat, NoColor, 0)
+	assert.False(t, l.V(1).Enabled())
+
+	assert.NoError(t, l.SetVModule("other=5,vmodule_test=2"))
+	assert.Equal(t, "other=5,vmodule_test=2", l.VModule())
+	assert.True(t, l.V(2).Enabled())
+	assert.False(t, l.V(3).Enabled())
+
+	// a pattern with a slash matches the package directory
+	assert.NoError(t, l.SetVModule("*/vmodule_*=4"))
+	assert.True(t, l.V(4).Enabled())
+
+	// a failed spec keeps the previous one
+	assert.Error(t, l.SetVModule("bad"))
+	assert.True(t, l.V(4).Enabled())
+
+	assert.NoError(t, l.SetVModule(""))
+	assert.False(t, l.V(1).Enabled())
+	assert.Equal(t, "", l.VModule())
+}
+
+func Test_VModule(t *testing.T) {
+
+	defer SetVModule("")
+
+	assert.False(t, bool(V(3)))
+	assert.NoError(t, SetVModule("vmodule_test=3"))
+	assert.True(t, bool(V(3)))
+	assert.False(t, bool(V(4)))
+}
+
+func Test_VDepth(t *testing.T) {
+
+	l := New(nil, DefaultFormat, NoColor, 0)
+	assert.NoError(t, l.SetVModule("vmodule_test=2"))
+
+	// testing.tRunner is the caller one frame up
+	assert.True(t, l.VDepth(0, 2).Enabled())
+	assert.False(t, l.VDepth(1, 2).Enabled())
+}